@@ -0,0 +1,38 @@
+// coordinatord runs the gRPC coordination service that scenario_runner
+// instances talk to when started with --coord=host:port, replacing the
+// contact_dir filesystem exchange with an in-memory registry and barrier.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net"
+
+	"google.golang.org/grpc"
+
+	"main/coord"
+)
+
+func main() {
+	var addr string
+	flag.StringVar(&addr, "addr", ":7777", "address to listen on")
+	flag.Parse()
+
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Fatalf("Error listening on %s: %v", addr, err)
+	}
+
+	coordinator := coord.NewCoordinator(func(e coord.Event) {
+		fmt.Printf("%d %d %s %s %s %s\n", e.WallMs, e.LamportSeq, e.Type, e.HostID, e.WorldSession, e.PeerSession)
+	})
+
+	s := grpc.NewServer()
+	coord.RegisterService(s, coordinator)
+
+	log.Printf("coordinatord listening on %s", addr)
+	if err := s.Serve(lis); err != nil {
+		log.Fatalf("Error serving: %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+// logmerge reads every peer's CBOR event log from a scenario run (as
+// written by scenario_runner's --out files) and emits them as a single,
+// causally ordered JSON-lines stream, resolving concurrent events by
+// (lamport_seq, host_id).
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"main/eventlog"
+)
+
+func main() {
+	var dir string
+	var outPath string
+	flag.StringVar(&dir, "dir", "", "directory containing per-host CBOR event logs")
+	flag.StringVar(&outPath, "out", "", "path to write the merged JSON-lines stream (default: stdout)")
+	flag.Parse()
+
+	if dir == "" {
+		log.Fatal("missing required --dir")
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		log.Fatalf("Error reading %s: %v", dir, err)
+	}
+
+	var records []eventlog.Record
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		records = append(records, readLog(path)...)
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].LamportSeq != records[j].LamportSeq {
+			return records[i].LamportSeq < records[j].LamportSeq
+		}
+		return records[i].HostID < records[j].HostID
+	})
+
+	out := os.Stdout
+	if outPath != "" {
+		out, err = os.Create(outPath)
+		if err != nil {
+			log.Fatalf("Error creating %s: %v", outPath, err)
+		}
+		defer out.Close()
+	}
+
+	enc := json.NewEncoder(out)
+	for _, rec := range records {
+		if err := enc.Encode(rec); err != nil {
+			log.Fatalf("Error encoding record: %v", err)
+		}
+	}
+}
+
+func readLog(path string) []eventlog.Record {
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatalf("Error opening %s: %v", path, err)
+	}
+	defer f.Close()
+
+	r := eventlog.NewReader(f)
+	var records []eventlog.Record
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Fatalf("Error reading %s: %v", path, err)
+		}
+		records = append(records, rec)
+	}
+	return records
+}
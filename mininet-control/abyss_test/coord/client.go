@@ -0,0 +1,91 @@
+package coord
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Client is a thin wrapper around a grpc.ClientConn dialed to a coordinator
+// process, exposing the Coordination RPCs as plain Go methods.
+type Client struct {
+	cc *grpc.ClientConn
+}
+
+// Dial connects to a coordinator listening at addr (host:port).
+func Dial(addr string) (*Client, error) {
+	cc, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	return &Client{cc: cc}, nil
+}
+
+// Close tears down the underlying connection.
+func (c *Client) Close() error {
+	return c.cc.Close()
+}
+
+func (c *Client) RegisterPeer(ctx context.Context, req *RegisterPeerRequest) (*RegisterPeerResponse, error) {
+	resp := new(RegisterPeerResponse)
+	if err := c.cc.Invoke(ctx, "/"+serviceName+"/RegisterPeer", req, resp, grpc.CallContentSubtype(jsonContentSubtype)); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *Client) GetPeer(ctx context.Context, req *GetPeerRequest) (*GetPeerResponse, error) {
+	resp := new(GetPeerResponse)
+	if err := c.cc.Invoke(ctx, "/"+serviceName+"/GetPeer", req, resp, grpc.CallContentSubtype(jsonContentSubtype)); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *Client) Barrier(ctx context.Context, req *BarrierRequest) (*BarrierResponse, error) {
+	resp := new(BarrierResponse)
+	if err := c.cc.Invoke(ctx, "/"+serviceName+"/Barrier", req, resp, grpc.CallContentSubtype(jsonContentSubtype)); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *Client) SubmitEvent(ctx context.Context, req *SubmitEventRequest) (*SubmitEventResponse, error) {
+	resp := new(SubmitEventResponse)
+	if err := c.cc.Invoke(ctx, "/"+serviceName+"/SubmitEvent", req, resp, grpc.CallContentSubtype(jsonContentSubtype)); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// PeerWatchClient is the client side of the WatchPeers server-streaming RPC.
+type PeerWatchClient interface {
+	Recv() (*PeerInfo, error)
+}
+
+type peerWatchClient struct {
+	grpc.ClientStream
+}
+
+func (c *peerWatchClient) Recv() (*PeerInfo, error) {
+	info := new(PeerInfo)
+	if err := c.ClientStream.RecvMsg(info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func (c *Client) WatchPeers(ctx context.Context, req *WatchPeersRequest) (PeerWatchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &serviceDesc.Streams[0], "/"+serviceName+"/WatchPeers", grpc.CallContentSubtype(jsonContentSubtype))
+	if err != nil {
+		return nil, err
+	}
+	if err := stream.SendMsg(req); err != nil {
+		return nil, err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return &peerWatchClient{ClientStream: stream}, nil
+}
@@ -0,0 +1,32 @@
+package coord
+
+import (
+	"encoding/json"
+
+	"google.golang.org/grpc/encoding"
+)
+
+// jsonContentSubtype is the grpc content-subtype jsonCodec registers under.
+// Client passes grpc.CallContentSubtype(jsonContentSubtype) on every
+// Invoke/NewStream so this service's calls pick it up without touching
+// grpc-go's default codec (registered under "proto"), which some other
+// client/server in this binary may still need for real protobuf.
+const jsonContentSubtype = "json"
+
+// jsonCodec is a grpc encoding.Codec that marshals request/response structs
+// as JSON instead of protobuf wire format. There is no generated
+// .proto/.pb.go pair for this service: the message and service shapes below
+// are the source of truth.
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error) { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+func (jsonCodec) Name() string { return jsonContentSubtype }
+
+// init registers jsonCodec under its own content-subtype rather than
+// grpc-go's default "proto", so it only applies to calls that opt in via
+// grpc.CallContentSubtype (see client.go) instead of silently replacing the
+// codec for every client/server in the binary.
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
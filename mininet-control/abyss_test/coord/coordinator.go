@@ -0,0 +1,163 @@
+package coord
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Coordinator is the in-memory, single-process implementation of Server. It
+// holds the contact registry that used to live in contact_dir and the join
+// barriers that used to be approximated by each host polling and retrying
+// independently.
+type Coordinator struct {
+	mu       sync.Mutex
+	peers    map[string]PeerInfo
+	order    []string
+	watchers map[chan PeerInfo]struct{}
+
+	barrierMu sync.Mutex
+	barriers  map[string]*barrier
+
+	lamportMu     sync.Mutex
+	maxLamportSeq uint64
+
+	onEvent func(Event)
+}
+
+// NewCoordinator returns a Coordinator ready to accept RegisterPeer calls.
+// onEvent, if non-nil, is invoked for every event submitted via SubmitEvent
+// (e.g. to append it to a log); it must not block for long.
+func NewCoordinator(onEvent func(Event)) *Coordinator {
+	return &Coordinator{
+		peers:    make(map[string]PeerInfo),
+		watchers: make(map[chan PeerInfo]struct{}),
+		barriers: make(map[string]*barrier),
+		onEvent:  onEvent,
+	}
+}
+
+func (c *Coordinator) RegisterPeer(ctx context.Context, req *RegisterPeerRequest) (*RegisterPeerResponse, error) {
+	c.mu.Lock()
+	if _, exists := c.peers[req.Peer.ID]; !exists {
+		c.order = append(c.order, req.Peer.ID)
+	}
+	c.peers[req.Peer.ID] = req.Peer
+	for ch := range c.watchers {
+		select {
+		case ch <- req.Peer:
+		default:
+			// slow watcher; drop rather than block registration.
+		}
+	}
+	c.mu.Unlock()
+	return &RegisterPeerResponse{}, nil
+}
+
+func (c *Coordinator) GetPeer(ctx context.Context, req *GetPeerRequest) (*GetPeerResponse, error) {
+	c.mu.Lock()
+	peer, ok := c.peers[req.ID]
+	c.mu.Unlock()
+	return &GetPeerResponse{Peer: peer, Found: ok}, nil
+}
+
+func (c *Coordinator) WatchPeers(req *WatchPeersRequest, stream PeerWatchStream) error {
+	ch := make(chan PeerInfo, 64)
+
+	c.mu.Lock()
+	backlog := make([]PeerInfo, len(c.order))
+	for i, id := range c.order {
+		backlog[i] = c.peers[id]
+	}
+	c.watchers[ch] = struct{}{}
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.watchers, ch)
+		c.mu.Unlock()
+	}()
+
+	for _, peer := range backlog {
+		if err := stream.Send(&peer); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case peer := <-ch:
+			if err := stream.Send(&peer); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
+
+// barrier tracks which peer IDs have arrived at a given scenario step and
+// closes done once NPeers distinct peers have.
+type barrier struct {
+	mu      sync.Mutex
+	arrived map[string]struct{}
+	nPeers  int
+	done    chan struct{}
+}
+
+func (c *Coordinator) getBarrier(step string, nPeers int) *barrier {
+	c.barrierMu.Lock()
+	defer c.barrierMu.Unlock()
+
+	b, ok := c.barriers[step]
+	if !ok {
+		b = &barrier{
+			arrived: make(map[string]struct{}, nPeers),
+			nPeers:  nPeers,
+			done:    make(chan struct{}),
+		}
+		c.barriers[step] = b
+	}
+	return b
+}
+
+func (c *Coordinator) Barrier(ctx context.Context, req *BarrierRequest) (*BarrierResponse, error) {
+	if req.NPeers <= 0 {
+		return nil, fmt.Errorf("coord: barrier %q: n_peers must be positive", req.Step)
+	}
+
+	b := c.getBarrier(req.Step, req.NPeers)
+
+	b.mu.Lock()
+	b.arrived[req.PeerID] = struct{}{}
+	if len(b.arrived) >= b.nPeers {
+		select {
+		case <-b.done:
+		default:
+			close(b.done)
+		}
+	}
+	b.mu.Unlock()
+
+	select {
+	case <-b.done:
+		return &BarrierResponse{}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *Coordinator) SubmitEvent(ctx context.Context, req *SubmitEventRequest) (*SubmitEventResponse, error) {
+	if c.onEvent != nil {
+		c.onEvent(req.Event)
+	}
+
+	c.lamportMu.Lock()
+	if req.Event.LamportSeq > c.maxLamportSeq {
+		c.maxLamportSeq = req.Event.LamportSeq
+	}
+	global := c.maxLamportSeq
+	c.lamportMu.Unlock()
+
+	return &SubmitEventResponse{GlobalLamportSeq: global}, nil
+}
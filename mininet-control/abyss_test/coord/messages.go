@@ -0,0 +1,67 @@
+package coord
+
+// PeerInfo is the registry entry a host publishes about itself: everything a
+// peer previously learned by reading {id}_rc/{id}_hs/{id}_id out of
+// contact_dir.
+type PeerInfo struct {
+	ID                      string `json:"id"`
+	RootCertificate         string `json:"root_certificate"`
+	HandshakeKeyCertificate string `json:"handshake_key_certificate"`
+	IDHash                  string `json:"id_hash"`
+}
+
+type RegisterPeerRequest struct {
+	Peer PeerInfo `json:"peer"`
+}
+type RegisterPeerResponse struct{}
+
+type GetPeerRequest struct {
+	ID string `json:"id"`
+}
+type GetPeerResponse struct {
+	Peer  PeerInfo `json:"peer"`
+	Found bool     `json:"found"`
+}
+
+// WatchPeersRequest has no fields; WatchPeers streams every already
+// registered peer followed by subsequently registered ones. Each watcher
+// has a 64-entry buffer; a registration that arrives while it is full is
+// not delivered to that watcher.
+type WatchPeersRequest struct{}
+
+// BarrierRequest asks the coordinator to block the caller until every one of
+// NPeers participants has called Barrier with the same Step.
+type BarrierRequest struct {
+	Step   string `json:"step"`
+	PeerID string `json:"peer_id"`
+	NPeers int    `json:"n_peers"`
+}
+type BarrierResponse struct{}
+
+// Event mirrors one line of the ScenarioRunner's event log (E/J/L/X) plus the
+// host it came from, so a single coordinator can reconstruct a global,
+// cross-host event stream instead of each host appending to its own file.
+// LamportSeq lets that reconstruction be causally ordered instead of relying
+// on each host's unsynchronized wall clock; see mininet-control/abyss_test/eventlog.
+type Event struct {
+	HostID       string `json:"host_id"`
+	WallMs       int64  `json:"wall_ms"`
+	LamportSeq   uint64 `json:"lamport_seq"`
+	Type         string `json:"type"` // "E", "J", "L", or "X"
+	WorldSession string `json:"world_session,omitempty"`
+	PeerSession  string `json:"peer_session,omitempty"`
+}
+
+type SubmitEventRequest struct {
+	Event Event `json:"event"`
+}
+
+// SubmitEventResponse hands back the Lamport clock state needed to keep
+// every host's eventlog.Clock synchronized: GlobalLamportSeq is the highest
+// LamportSeq the coordinator has observed in any host's Event so far,
+// across this call included. Callers fold it into their own clock with
+// eventlog.Clock.Observe, so a round trip through SubmitEvent is what
+// actually carries a remote timestamp between hosts.
+type SubmitEventResponse struct {
+	GlobalLamportSeq uint64 `json:"global_lamport_seq"`
+}
@@ -0,0 +1,128 @@
+package coord
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+// serviceName is the gRPC service path segment, kept stable even though this
+// package hand-rolls its ServiceDesc instead of generating it from a .proto.
+const serviceName = "coord.Coordination"
+
+// Server is the interface a coordinator process implements. It plays the
+// role a generated "CoordinationServer" interface would.
+type Server interface {
+	RegisterPeer(context.Context, *RegisterPeerRequest) (*RegisterPeerResponse, error)
+	GetPeer(context.Context, *GetPeerRequest) (*GetPeerResponse, error)
+	WatchPeers(*WatchPeersRequest, PeerWatchStream) error
+	Barrier(context.Context, *BarrierRequest) (*BarrierResponse, error)
+	SubmitEvent(context.Context, *SubmitEventRequest) (*SubmitEventResponse, error)
+}
+
+// PeerWatchStream is the server side of the WatchPeers server-streaming RPC.
+type PeerWatchStream interface {
+	Send(*PeerInfo) error
+	grpc.ServerStream
+}
+
+type peerWatchStream struct {
+	grpc.ServerStream
+}
+
+func (s *peerWatchStream) Send(p *PeerInfo) error {
+	return s.ServerStream.SendMsg(p)
+}
+
+// RegisterService registers srv on s under the RPC names dialed by Client.
+func RegisterService(s grpc.ServiceRegistrar, srv Server) {
+	s.RegisterService(&serviceDesc, srv)
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*Server)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "RegisterPeer",
+			Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+				req := new(RegisterPeerRequest)
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				if interceptor == nil {
+					return srv.(Server).RegisterPeer(ctx, req)
+				}
+				info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + serviceName + "/RegisterPeer"}
+				handler := func(ctx context.Context, req any) (any, error) {
+					return srv.(Server).RegisterPeer(ctx, req.(*RegisterPeerRequest))
+				}
+				return interceptor(ctx, req, info, handler)
+			},
+		},
+		{
+			MethodName: "GetPeer",
+			Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+				req := new(GetPeerRequest)
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				if interceptor == nil {
+					return srv.(Server).GetPeer(ctx, req)
+				}
+				info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + serviceName + "/GetPeer"}
+				handler := func(ctx context.Context, req any) (any, error) {
+					return srv.(Server).GetPeer(ctx, req.(*GetPeerRequest))
+				}
+				return interceptor(ctx, req, info, handler)
+			},
+		},
+		{
+			MethodName: "Barrier",
+			Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+				req := new(BarrierRequest)
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				if interceptor == nil {
+					return srv.(Server).Barrier(ctx, req)
+				}
+				info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + serviceName + "/Barrier"}
+				handler := func(ctx context.Context, req any) (any, error) {
+					return srv.(Server).Barrier(ctx, req.(*BarrierRequest))
+				}
+				return interceptor(ctx, req, info, handler)
+			},
+		},
+		{
+			MethodName: "SubmitEvent",
+			Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+				req := new(SubmitEventRequest)
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				if interceptor == nil {
+					return srv.(Server).SubmitEvent(ctx, req)
+				}
+				info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + serviceName + "/SubmitEvent"}
+				handler := func(ctx context.Context, req any) (any, error) {
+					return srv.(Server).SubmitEvent(ctx, req.(*SubmitEventRequest))
+				}
+				return interceptor(ctx, req, info, handler)
+			},
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchPeers",
+			ServerStreams: true,
+			Handler: func(srv any, stream grpc.ServerStream) error {
+				req := new(WatchPeersRequest)
+				if err := stream.RecvMsg(req); err != nil {
+					return err
+				}
+				return srv.(Server).WatchPeers(req, &peerWatchStream{ServerStream: stream})
+			},
+		},
+	},
+}
@@ -0,0 +1,37 @@
+package eventlog
+
+import "sync"
+
+// Clock is a Lamport logical clock. It ticks on every locally emitted or
+// handled event, and Observe folds in a remote timestamp whenever one is
+// learned from elsewhere — which, since abyss_core's AND protocol carries no
+// per-event clock field of its own, is in coordinator mode only: ScenarioRunner
+// calls Observe with the GlobalLamportSeq every coord.Client.SubmitEvent
+// response carries back, the highest LamportSeq the coordinator has seen
+// from any host so far. In legacy contact_dir mode, with no shared
+// coordinator to round-trip through, Clock only ever ticks locally.
+type Clock struct {
+	mu sync.Mutex
+	t  uint64
+}
+
+// Tick advances the clock for a purely local event and returns the new
+// value.
+func (c *Clock) Tick() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t++
+	return c.t
+}
+
+// Observe folds in a timestamp received from a peer (remote) and advances
+// the clock past it, per the standard Lamport rule: max(local, remote)+1.
+func (c *Clock) Observe(remote uint64) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if remote > c.t {
+		c.t = remote
+	}
+	c.t++
+	return c.t
+}
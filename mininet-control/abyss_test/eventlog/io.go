@@ -0,0 +1,76 @@
+package eventlog
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// Writer appends length-prefixed CBOR Records to an io.Writer. Safe for
+// concurrent use.
+type Writer struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriter wraps w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Write encodes rec as CBOR and appends it to the stream as a 4-byte
+// big-endian length prefix followed by the payload, so Reader can frame
+// records without a delimiter that could appear in the payload itself.
+func (w *Writer) Write(rec Record) error {
+	payload, err := cbor.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("eventlog: marshal: %w", err)
+	}
+
+	var lenPrefix [4]byte
+	binary.BigEndian.PutUint32(lenPrefix[:], uint32(len(payload)))
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, err := w.w.Write(lenPrefix[:]); err != nil {
+		return err
+	}
+	_, err = w.w.Write(payload)
+	return err
+}
+
+// Reader reads length-prefixed CBOR Records back out of an io.Reader, in
+// the framing Writer produces.
+type Reader struct {
+	r io.Reader
+}
+
+// NewReader wraps r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: r}
+}
+
+// Read returns the next Record, or io.EOF once the stream is exhausted.
+func (r *Reader) Read() (Record, error) {
+	var lenPrefix [4]byte
+	if _, err := io.ReadFull(r.r, lenPrefix[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return Record{}, fmt.Errorf("eventlog: truncated length prefix: %w", err)
+		}
+		return Record{}, err
+	}
+
+	payload := make([]byte, binary.BigEndian.Uint32(lenPrefix[:]))
+	if _, err := io.ReadFull(r.r, payload); err != nil {
+		return Record{}, fmt.Errorf("eventlog: truncated record: %w", err)
+	}
+
+	var rec Record
+	if err := cbor.Unmarshal(payload, &rec); err != nil {
+		return Record{}, fmt.Errorf("eventlog: unmarshal: %w", err)
+	}
+	return rec, nil
+}
@@ -0,0 +1,20 @@
+// Package eventlog is the structured, causally-ordered replacement for the
+// ScenarioRunner's old "<wall_ms> <type> <session>\n" text output. Every
+// record carries a Lamport timestamp alongside wall-clock millis so a
+// separate tool (cmd/logmerge) can reconstruct a single, causally consistent
+// event order across every peer in a run, instead of only being able to
+// compare wall clocks that were never synchronized between hosts.
+package eventlog
+
+// Record is one scenario event. WorldSession/PeerSession are the string form
+// of the relevant uuid.UUID(s); either may be empty when not applicable to
+// EventType (e.g. "E" has no peer session).
+type Record struct {
+	HostID       string `cbor:"host_id"`
+	WallMs       int64  `cbor:"wall_ms"`
+	LamportSeq   uint64 `cbor:"lamport_seq"`
+	EventType    string `cbor:"event_type"` // "E", "J", "L", or "X"
+	WorldSession string `cbor:"world_session,omitempty"`
+	PeerSession  string `cbor:"peer_session,omitempty"`
+	Extra        string `cbor:"extra,omitempty"`
+}
@@ -1,16 +1,20 @@
 package main
 
 import (
+	"context"
 	"crypto/ed25519"
-	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 	"path"
+	"time"
 
 	"github.com/kadmila/Abyss-Browser/abyss_core/ahost"
 	"golang.org/x/crypto/ssh"
+
+	"main/coord"
+	"main/scenario"
 )
 
 func main() {
@@ -20,25 +24,33 @@ func main() {
 	var contact_dir string
 	var scenario_path string
 	var output_path string
+	var netem_path string
+	var coord_addr string
+	var time_start int64
+	var duration int64
 	flag.IntVar(&n_peer, "n_peer", 0, "number of peers")
 	flag.StringVar(&id, "id", "", "host id")
 	flag.StringVar(&contact_dir, "contact_dir", "", "path to directory for sharing contact information")
-	flag.StringVar(&scenario_path, "scenario", "", "path to scenario JSON file")
+	flag.StringVar(&scenario_path, "scenario", "", "path to scenario JSON file (v1 array or v2 {version,steps} envelope)")
 	flag.StringVar(&output_path, "out", "", "path to output file")
+	flag.StringVar(&netem_path, "netem_out", "", "path to write set_link overrides to, for an external tc/netem controller; unset disables set_link")
+	flag.StringVar(&coord_addr, "coord", "", "host:port of a coordinatord instance; enables gRPC-based peer exchange instead of contact_dir polling")
+	flag.Int64Var(&time_start, "time_start", time.Now().Unix(), "unix time (seconds) the scenario's step timestamps are relative to")
+	flag.Int64Var(&duration, "duration", 3600, "scenario duration in seconds")
 	flag.Parse()
 
 	// Parse scenario file if provided
-	var scenario []map[string]string
+	scen := &scenario.Scenario{}
 	if scenario_path != "" {
 		scenarioData, err := os.ReadFile(scenario_path)
 		if err != nil {
 			log.Fatalf("Error reading scenario file: %v", err)
 		}
-		err = json.Unmarshal(scenarioData, &scenario)
+		scen, err = scenario.Load(scenarioData)
 		if err != nil {
-			log.Fatalf("Error parsing scenario JSON: %v", err)
+			log.Fatalf("Error parsing scenario file: %v", err)
 		}
-		log.Printf("Loaded scenario with %d entries", len(scenario))
+		log.Printf("Loaded scenario with %d actions", len(scen.Actions))
 	}
 
 	// Read ../credentials/{id}.pem and parse key
@@ -69,32 +81,52 @@ func main() {
 
 	go host.Serve()
 
-	// Write contact information
-	rc_f, err := os.OpenFile(path.Join(contact_dir, id+"_rc"), os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		log.Fatal(err)
-	}
-	if _, err := rc_f.WriteString(host.RootCertificate()); err != nil {
-		log.Fatal(err)
-	}
-	rc_f.Close() // Ensure the file is closed
-	hs_f, err := os.OpenFile(path.Join(contact_dir, id+"_hs"), os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		log.Fatal(err)
-	}
-	if _, err := hs_f.WriteString(host.HandshakeKeyCertificate()); err != nil {
-		log.Fatal(err)
-	}
-	hs_f.Close() // Ensure the file is closed
-	id_f, err := os.OpenFile(path.Join(contact_dir, id+"_id"), os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		log.Fatal(err)
-	}
-	if _, err := id_f.WriteString(host.ID()); err != nil {
-		log.Fatal(err)
+	// Publish contact information: via the coordinator when --coord is set,
+	// otherwise the legacy contact_dir files that every peer polls.
+	var coord_client *coord.Client
+	if coord_addr != "" {
+		coord_client, err = coord.Dial(coord_addr)
+		if err != nil {
+			log.Fatalf("Error dialing coordinator at %s: %v", coord_addr, err)
+		}
+		_, err = coord_client.RegisterPeer(context.Background(), &coord.RegisterPeerRequest{
+			Peer: coord.PeerInfo{
+				ID:                      id,
+				RootCertificate:         host.RootCertificate(),
+				HandshakeKeyCertificate: host.HandshakeKeyCertificate(),
+				IDHash:                  host.ID(),
+			},
+		})
+		if err != nil {
+			log.Fatalf("Error registering with coordinator: %v", err)
+		}
+	} else {
+		rc_f, err := os.OpenFile(path.Join(contact_dir, id+"_rc"), os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if _, err := rc_f.WriteString(host.RootCertificate()); err != nil {
+			log.Fatal(err)
+		}
+		rc_f.Close() // Ensure the file is closed
+		hs_f, err := os.OpenFile(path.Join(contact_dir, id+"_hs"), os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if _, err := hs_f.WriteString(host.HandshakeKeyCertificate()); err != nil {
+			log.Fatal(err)
+		}
+		hs_f.Close() // Ensure the file is closed
+		id_f, err := os.OpenFile(path.Join(contact_dir, id+"_id"), os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if _, err := id_f.WriteString(host.ID()); err != nil {
+			log.Fatal(err)
+		}
+		id_f.Close() // Ensure the file is closed
 	}
-	id_f.Close() // Ensure the file is closed
 
-	scenario_runner := NewScenarioRunner(contact_dir, scenario, host, output_path)
+	scenario_runner := NewScenarioRunner(id, contact_dir, time_start, duration, scen, host, output_path, netem_path, coord_client, n_peer)
 	scenario_runner.Run()
 }
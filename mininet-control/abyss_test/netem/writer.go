@@ -0,0 +1,51 @@
+// Package netem writes the sidecar file ScenarioRunner's "set_link" action
+// appends to: one JSON object per line describing a rate/loss/delay override
+// for a simulated link. It's consumed out-of-process by a tc/netem
+// controller, so the format is plain JSON-lines rather than eventlog's
+// length-prefixed CBOR.
+package netem
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// LinkUpdate is one "set_link" override, from HostID's point of view,
+// targeting TargetID. A nil field means "leave this characteristic
+// unchanged".
+type LinkUpdate struct {
+	WallMs   int64    `json:"wall_ms"`
+	HostID   string   `json:"host_id"`
+	TargetID string   `json:"target_id"`
+	RateKbps *int     `json:"rate_kbps,omitempty"`
+	LossPct  *float64 `json:"loss_pct,omitempty"`
+	DelayMs  *int     `json:"delay_ms,omitempty"`
+}
+
+// Writer appends newline-delimited JSON LinkUpdates to an io.Writer. Safe
+// for concurrent use.
+type Writer struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriter wraps w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Write encodes update as JSON and appends it followed by a newline.
+func (w *Writer) Write(update LinkUpdate) error {
+	payload, err := json.Marshal(update)
+	if err != nil {
+		return fmt.Errorf("netem: marshal: %w", err)
+	}
+	payload = append(payload, '\n')
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, err = w.w.Write(payload)
+	return err
+}
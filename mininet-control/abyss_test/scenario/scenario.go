@@ -0,0 +1,137 @@
+// Package scenario parses the ScenarioRunner's step DSL: a scenario file
+// describing what each simulated peer does and when. The original format
+// (v1) is a flat `[]map[string]string` of "add"/"dial"/"join"/"open" actions
+// against an absolute "time" field. v2 wraps that in a versioned envelope,
+// `{"version":2,"steps":[...]}`, and adds "repeat"/"every" control blocks,
+// `random_peer` selectors, and the "leave"/"publish_object"/
+// "delete_object"/"set_link"/"assert" actions. Load accepts either format
+// and always returns a flat, time-ordered Scenario: v2's control blocks are
+// expanded at load time, so ScenarioRunner only ever walks a plain action
+// list.
+package scenario
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+)
+
+// Scenario is the flattened, ready-to-run form of a scenario file.
+type Scenario struct {
+	// Seed is the RNG seed ScenarioRunner should use for random_peer
+	// selection, so a run can be replayed deterministically. Zero means the
+	// scenario didn't request determinism; callers should fall back to a
+	// time-based seed and log it.
+	Seed int64
+	// Peers is the scenario's declared peer roster, used by random_peer
+	// selectors with from:"world". Empty for v1 scenarios and v2 scenarios
+	// that don't declare one.
+	Peers []string
+	// Actions is every leaf action, in non-decreasing Time order.
+	Actions []Action
+}
+
+// Action is one executable scenario step. Which fields are meaningful
+// depends on Do; see the field comments below and the individual action
+// implementations in steps.go.
+type Action struct {
+	// Time is relative to the scenario's time_start, in seconds, matching
+	// the original "time" field ScenarioRunner.Run waits on.
+	Time int64
+	// Do names the registered Step that executes this action, e.g. "dial"
+	// or "set_link".
+	Do string
+
+	// ID is the literal peer id this action targets. Mutually exclusive
+	// with Peer; exactly one must be set for actions that take a peer.
+	ID string
+	// Peer randomly selects the target peer id at execution time instead
+	// of naming one literally.
+	Peer *PeerSelector
+
+	// Object is the payload for "publish_object".
+	Object *ObjectSpec
+	// ObjectID is the uuid.UUID string of the object "delete_object"
+	// removes.
+	ObjectID string
+
+	// Link is the rate/loss/delay override for "set_link".
+	Link *LinkSpec
+
+	// Assert is the expectation "assert" checks.
+	Assert *AssertSpec
+}
+
+// PeerSelector picks a peer id at execution time rather than the scenario
+// author naming one literally, so the same scenario file can exercise
+// different peers across runs or repeat iterations.
+type PeerSelector struct {
+	// From is "known" (peers this host has AppendKnownPeer'd), "joined"
+	// (peers currently sharing this host's open world), or "world" (the
+	// scenario's declared Peers roster).
+	From string
+}
+
+// ObjectSpec is the payload of a "publish_object" action, mirroring
+// and.ObjectInfo minus the ID, which ScenarioRunner generates.
+type ObjectSpec struct {
+	Addr      string
+	Transform [7]float32
+}
+
+// LinkSpec overrides the simulated network path to TargetID. A nil field
+// leaves that characteristic unchanged. ScenarioRunner doesn't apply these
+// itself; it appends them to a netem sidecar file an external tc/netem
+// controller watches.
+type LinkSpec struct {
+	TargetID string
+	RateKbps *int
+	LossPct  *float64
+	DelayMs  *int
+}
+
+// AssertSpec is the expectation an "assert" action checks. At least one of
+// WorldMembers or EventCount must be set; ScenarioRunner fails the run (via
+// log.Fatalf) if the live state doesn't match.
+type AssertSpec struct {
+	// WorldMembers, if set, is the exact number of peer sessions the
+	// runner's currently open world must have.
+	WorldMembers *int
+	// EventCount, if set, is the exact number of times an event type must
+	// have been logged so far.
+	EventCount *EventCountAssert
+}
+
+// EventCountAssert checks the running count of logged events of Type
+// ("E", "J", "L", or "X") against Count.
+type EventCountAssert struct {
+	Type  string
+	Count int
+}
+
+// Load parses scenario file contents as either the legacy v1 array or the
+// versioned v2 envelope, detected from the first non-whitespace byte, and
+// returns a flattened, time-ordered Scenario.
+func Load(data []byte) (*Scenario, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return &Scenario{}, nil
+	}
+
+	var s *Scenario
+	var err error
+	switch trimmed[0] {
+	case '[':
+		s, err = loadV1(trimmed)
+	case '{':
+		s, err = loadV2(trimmed)
+	default:
+		return nil, fmt.Errorf("scenario: file starts with %q, expected '[' (v1) or '{' (v2)", trimmed[0])
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(s.Actions, func(i, j int) bool { return s.Actions[i].Time < s.Actions[j].Time })
+	return s, nil
+}
@@ -0,0 +1,38 @@
+package scenario
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// loadV1 parses the legacy `[]map[string]string` scenario format: every
+// entry is a "do"/"id"/"time" leaf action, "time" given as a decimal
+// string. Only "add", "dial", "join", and "open" were ever produced by this
+// format, but Load doesn't reject other Do values here; steps.go's registry
+// decides what's runnable.
+func loadV1(data []byte) (*Scenario, error) {
+	var raw []map[string]string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("scenario: parse v1: %w", err)
+	}
+
+	actions := make([]Action, 0, len(raw))
+	for i, step := range raw {
+		timeStr, ok := step["time"]
+		if !ok {
+			return nil, fmt.Errorf("scenario: v1 step %d missing \"time\"", i)
+		}
+		t, err := strconv.ParseInt(timeStr, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("scenario: v1 step %d has invalid \"time\" %q: %w", i, timeStr, err)
+		}
+		actions = append(actions, Action{
+			Time: t,
+			Do:   step["do"],
+			ID:   step["id"],
+		})
+	}
+
+	return &Scenario{Actions: actions}, nil
+}
@@ -0,0 +1,214 @@
+package scenario
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+)
+
+// rawFormat is the top-level v2 envelope: {"version":2, "seed":..., "peers":
+// [...], "steps":[...]}.
+type rawFormat struct {
+	Version int       `json:"version"`
+	Seed    int64     `json:"seed,omitempty"`
+	Peers   []string  `json:"peers,omitempty"`
+	Steps   []rawStep `json:"steps"`
+}
+
+// rawStep is either a leaf action (Do set) or a control block (Repeat or
+// Every set, with nested Steps). flattenSteps rejects a step that sets both
+// Repeat and Every; a step with either one set is expanded as a control
+// block, and any Do on it is ignored.
+type rawStep struct {
+	// Leaf action fields.
+	Time       int64            `json:"time,omitempty"`
+	Do         string           `json:"do,omitempty"`
+	ID         string           `json:"id,omitempty"`
+	RandomPeer *rawPeerSelector `json:"random_peer,omitempty"`
+	Object     *rawObjectSpec   `json:"object,omitempty"`
+	ObjectID   string           `json:"object_id,omitempty"`
+	Link       *rawLinkSpec     `json:"link,omitempty"`
+	Assert     *rawAssertSpec   `json:"assert,omitempty"`
+
+	// Control-block fields.
+	Repeat *rawCycle `json:"repeat,omitempty"`
+	Every  *rawCycle `json:"every,omitempty"`
+	Steps  []rawStep `json:"steps,omitempty"`
+}
+
+// rawCycle configures a "repeat" or "every" control block: repeat its Steps
+// Count times, or until iteration base time UntilTime, spaced IntervalSec
+// apart (IntervalSec is required for "every", and for "repeat" whenever
+// UntilTime is the stop condition; a "repeat" with Count and IntervalSec 0
+// runs its iterations back-to-back). Jitter, if set, perturbs each
+// iteration's base time.
+type rawCycle struct {
+	Count       int        `json:"count,omitempty"`
+	UntilTime   int64      `json:"until_time,omitempty"`
+	IntervalSec int64      `json:"interval,omitempty"`
+	Jitter      *rawJitter `json:"jitter,omitempty"`
+}
+
+// rawJitter perturbs a cycle's iteration base time by a random offset in
+// seconds, sampled from a uniform [Min,Max] or normal(0,Stddev) distribution.
+type rawJitter struct {
+	Dist   string  `json:"dist"` // "uniform" or "normal"
+	Min    float64 `json:"min,omitempty"`
+	Max    float64 `json:"max,omitempty"`
+	Stddev float64 `json:"stddev,omitempty"`
+}
+
+type rawPeerSelector struct {
+	From string `json:"from"`
+}
+
+type rawObjectSpec struct {
+	Addr      string     `json:"addr"`
+	Transform [7]float32 `json:"transform,omitempty"`
+}
+
+type rawLinkSpec struct {
+	TargetID string   `json:"target_id"`
+	RateKbps *int     `json:"rate_kbps,omitempty"`
+	LossPct  *float64 `json:"loss_pct,omitempty"`
+	DelayMs  *int     `json:"delay_ms,omitempty"`
+}
+
+type rawAssertSpec struct {
+	WorldMembers *int              `json:"world_members,omitempty"`
+	EventCount   *EventCountAssert `json:"event_count,omitempty"`
+}
+
+// loadV2 parses the versioned envelope and expands every "repeat"/"every"
+// control block into concrete, time-stamped leaf actions.
+func loadV2(data []byte) (*Scenario, error) {
+	var raw rawFormat
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("scenario: parse v2: %w", err)
+	}
+	if raw.Version != 2 {
+		return nil, fmt.Errorf("scenario: unsupported version %d", raw.Version)
+	}
+
+	// Jitter sampling only affects load-time flattening of repeat/every
+	// iteration times, not random_peer selection (which ScenarioRunner does
+	// at execution time against live state); seeding it from the scenario's
+	// seed still makes a given scenario file flatten identically every load.
+	rng := rand.New(rand.NewSource(raw.Seed))
+
+	var actions []Action
+	if err := flattenSteps(raw.Steps, 0, rng, &actions); err != nil {
+		return nil, err
+	}
+
+	return &Scenario{Seed: raw.Seed, Peers: raw.Peers, Actions: actions}, nil
+}
+
+// flattenSteps appends every leaf action reachable from steps to out, with
+// Time resolved relative to base (the enclosing block's iteration start).
+func flattenSteps(steps []rawStep, base int64, rng *rand.Rand, out *[]Action) error {
+	for i, step := range steps {
+		switch {
+		case step.Repeat != nil && step.Every != nil:
+			return fmt.Errorf("scenario: step %d has both \"repeat\" and \"every\"", i)
+
+		case step.Repeat != nil:
+			if err := flattenCycle(*step.Repeat, base+step.Time, step.Steps, nil, rng, out); err != nil {
+				return fmt.Errorf("scenario: step %d repeat: %w", i, err)
+			}
+
+		case step.Every != nil:
+			if step.Every.IntervalSec <= 0 {
+				return fmt.Errorf("scenario: step %d every: \"interval\" must be positive", i)
+			}
+			if err := flattenCycle(*step.Every, base+step.Time, step.Steps, step.Every.Jitter, rng, out); err != nil {
+				return fmt.Errorf("scenario: step %d every: %w", i, err)
+			}
+
+		default:
+			if step.Do == "" {
+				return fmt.Errorf("scenario: step %d has neither \"do\" nor a control block", i)
+			}
+			*out = append(*out, Action{
+				Time:     base + step.Time,
+				Do:       step.Do,
+				ID:       step.ID,
+				Peer:     toPeerSelector(step.RandomPeer),
+				Object:   toObjectSpec(step.Object),
+				ObjectID: step.ObjectID,
+				Link:     toLinkSpec(step.Link),
+				Assert:   toAssertSpec(step.Assert),
+			})
+		}
+	}
+	return nil
+}
+
+// flattenCycle expands one "repeat"/"every" block: it re-flattens children
+// against each iteration's base time until cycle's stop condition (Count or
+// UntilTime) is reached, whichever comes first. At least one of those must
+// be set, or the cycle would never terminate; if UntilTime is the only stop
+// condition, IntervalSec must also be positive, or iterBase would never
+// advance toward it either.
+func flattenCycle(cycle rawCycle, blockTime int64, children []rawStep, jitter *rawJitter, rng *rand.Rand, out *[]Action) error {
+	if cycle.Count <= 0 && cycle.UntilTime <= 0 {
+		return fmt.Errorf("neither \"count\" nor \"until_time\" set, would loop forever")
+	}
+	if cycle.Count <= 0 && cycle.UntilTime > 0 && cycle.IntervalSec <= 0 {
+		return fmt.Errorf("\"until_time\" set without \"count\" requires a positive \"interval\", or iterations would never advance")
+	}
+
+	for i := 0; cycle.Count <= 0 || i < cycle.Count; i++ {
+		iterBase := blockTime + int64(i)*cycle.IntervalSec
+		if jitter != nil {
+			iterBase += sampleJitter(jitter, rng)
+		}
+		if cycle.UntilTime > 0 && iterBase >= cycle.UntilTime {
+			break
+		}
+		if err := flattenSteps(children, iterBase, rng, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sampleJitter draws a random time offset (in whole seconds) from j's
+// distribution. An unrecognized Dist is treated as "uniform".
+func sampleJitter(j *rawJitter, rng *rand.Rand) int64 {
+	if j.Dist == "normal" {
+		return int64(rng.NormFloat64() * j.Stddev)
+	}
+	if j.Max <= j.Min {
+		return 0
+	}
+	return int64(j.Min + rng.Float64()*(j.Max-j.Min))
+}
+
+func toPeerSelector(p *rawPeerSelector) *PeerSelector {
+	if p == nil {
+		return nil
+	}
+	return &PeerSelector{From: p.From}
+}
+
+func toObjectSpec(o *rawObjectSpec) *ObjectSpec {
+	if o == nil {
+		return nil
+	}
+	return &ObjectSpec{Addr: o.Addr, Transform: o.Transform}
+}
+
+func toLinkSpec(l *rawLinkSpec) *LinkSpec {
+	if l == nil {
+		return nil
+	}
+	return &LinkSpec{TargetID: l.TargetID, RateKbps: l.RateKbps, LossPct: l.LossPct, DelayMs: l.DelayMs}
+}
+
+func toAssertSpec(a *rawAssertSpec) *AssertSpec {
+	if a == nil {
+		return nil
+	}
+	return &AssertSpec{WorldMembers: a.WorldMembers, EventCount: a.EventCount}
+}
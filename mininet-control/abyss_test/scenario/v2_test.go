@@ -0,0 +1,166 @@
+package scenario
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestFlattenCycle_RejectsBlocksThatWouldNeverTerminate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cycle   rawCycle
+		wantErr string
+	}{
+		{
+			name:    "neither count nor until_time",
+			cycle:   rawCycle{},
+			wantErr: "would loop forever",
+		},
+		{
+			name:    "until_time without interval",
+			cycle:   rawCycle{UntilTime: 100},
+			wantErr: "requires a positive \"interval\"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out []Action
+			err := flattenCycle(tt.cycle, 0, nil, nil, rand.New(rand.NewSource(0)), &out)
+			if err == nil {
+				t.Fatal("flattenCycle: want error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("flattenCycle error = %q, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFlattenCycle_AcceptsTerminatingBlocks(t *testing.T) {
+	tests := []struct {
+		name  string
+		cycle rawCycle
+	}{
+		{name: "count only", cycle: rawCycle{Count: 3}},
+		{name: "until_time with interval", cycle: rawCycle{UntilTime: 30, IntervalSec: 10}},
+		{name: "count and until_time", cycle: rawCycle{Count: 5, UntilTime: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out []Action
+			children := []rawStep{{Time: 0, Do: "assert"}}
+			if err := flattenCycle(tt.cycle, 0, children, nil, rand.New(rand.NewSource(0)), &out); err != nil {
+				t.Fatalf("flattenCycle: %v", err)
+			}
+			if len(out) == 0 {
+				t.Error("flattenCycle: want at least one flattened action")
+			}
+		})
+	}
+}
+
+func TestFlattenCycle_CountStopsAfterExactIterations(t *testing.T) {
+	var out []Action
+	children := []rawStep{{Time: 0, Do: "assert"}}
+	cycle := rawCycle{Count: 4, IntervalSec: 10}
+	if err := flattenCycle(cycle, 0, children, nil, rand.New(rand.NewSource(0)), &out); err != nil {
+		t.Fatalf("flattenCycle: %v", err)
+	}
+	if len(out) != 4 {
+		t.Fatalf("len(out) = %d, want 4", len(out))
+	}
+	for i, a := range out {
+		want := int64(i) * 10
+		if a.Time != want {
+			t.Errorf("out[%d].Time = %d, want %d", i, a.Time, want)
+		}
+	}
+}
+
+func TestFlattenCycle_UntilTimeStopsBeforeThreshold(t *testing.T) {
+	var out []Action
+	children := []rawStep{{Time: 0, Do: "assert"}}
+	// blockTime=0, interval=10, until_time=25: iterations at 0, 10, 20 run;
+	// 30 would be >= 25 so the loop must stop there, not run forever.
+	cycle := rawCycle{UntilTime: 25, IntervalSec: 10}
+	if err := flattenCycle(cycle, 0, children, nil, rand.New(rand.NewSource(0)), &out); err != nil {
+		t.Fatalf("flattenCycle: %v", err)
+	}
+	if len(out) != 3 {
+		t.Fatalf("len(out) = %d, want 3", len(out))
+	}
+}
+
+func TestLoadV2_RejectsStepWithBothRepeatAndEvery(t *testing.T) {
+	data := []byte(`{
+		"version": 2,
+		"steps": [
+			{"repeat": {"count": 2}, "every": {"interval": 5, "count": 2}, "steps": [{"do": "assert"}]}
+		]
+	}`)
+	if _, err := loadV2(data); err == nil {
+		t.Fatal("loadV2: want error for a step with both repeat and every, got nil")
+	}
+}
+
+func TestLoadV2_RejectsEveryWithoutPositiveInterval(t *testing.T) {
+	data := []byte(`{
+		"version": 2,
+		"steps": [
+			{"every": {"count": 2}, "steps": [{"do": "assert"}]}
+		]
+	}`)
+	if _, err := loadV2(data); err == nil {
+		t.Fatal("loadV2: want error for \"every\" without a positive interval, got nil")
+	}
+}
+
+func TestLoadV2_FlattensRepeatAndEveryToLeafActions(t *testing.T) {
+	data := []byte(`{
+		"version": 2,
+		"seed": 42,
+		"peers": ["a", "b"],
+		"steps": [
+			{"time": 0, "repeat": {"count": 2}, "steps": [{"do": "join"}]},
+			{"time": 100, "every": {"interval": 10, "count": 3}, "steps": [{"do": "assert"}]}
+		]
+	}`)
+	s, err := loadV2(data)
+	if err != nil {
+		t.Fatalf("loadV2: %v", err)
+	}
+	if len(s.Actions) != 5 {
+		t.Fatalf("len(Actions) = %d, want 5 (2 from repeat + 3 from every)", len(s.Actions))
+	}
+	if s.Seed != 42 {
+		t.Errorf("Seed = %d, want 42", s.Seed)
+	}
+}
+
+func TestLoadV2_JitterIsDeterministicForAGivenSeed(t *testing.T) {
+	data := []byte(`{
+		"version": 2,
+		"seed": 7,
+		"steps": [
+			{"time": 0, "every": {"interval": 10, "count": 5, "jitter": {"dist": "uniform", "min": -2, "max": 2}}, "steps": [{"do": "assert"}]}
+		]
+	}`)
+	first, err := loadV2(data)
+	if err != nil {
+		t.Fatalf("loadV2: %v", err)
+	}
+	second, err := loadV2(data)
+	if err != nil {
+		t.Fatalf("loadV2: %v", err)
+	}
+	if len(first.Actions) != len(second.Actions) {
+		t.Fatalf("len mismatch: %d vs %d", len(first.Actions), len(second.Actions))
+	}
+	for i := range first.Actions {
+		if first.Actions[i].Time != second.Actions[i].Time {
+			t.Errorf("action %d: Time = %d on first load, %d on second (same seed should replay identically)",
+				i, first.Actions[i].Time, second.Actions[i].Time)
+		}
+	}
+}
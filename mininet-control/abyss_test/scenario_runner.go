@@ -1,65 +1,278 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"math/rand"
 	"os"
 	"path"
-	"strconv"
+	"sort"
 	"sync"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/kadmila/Abyss-Browser/abyss_core/ahost"
 	"github.com/kadmila/Abyss-Browser/abyss_core/and"
+
+	"main/coord"
+	"main/eventlog"
+	"main/netem"
+	"main/scenario"
 )
 
+// barrierWait bounds how long a peer blocks in the coordinator's Barrier RPC
+// waiting for the rest of the scenario's peers to reach the same step.
+const barrierWait = 30 * time.Second
+
+// submitEventWait bounds how long logEvent blocks in the coordinator's
+// SubmitEvent RPC. Unlike Barrier, SubmitEvent never waits on other peers,
+// so it gets a much shorter budget; it's still a network round trip on
+// every scenario event, called with world_mtx held, so an unbounded
+// context would let one coordinator hiccup freeze the whole host.
+const submitEventWait = 5 * time.Second
+
+// getPeerRetries/getPeerInterval bound how long "add"/"dial" wait for a peer
+// to show up in the coordinator's registry (it may not have registered yet).
+const getPeerRetries = 100
+const getPeerInterval = 100 * time.Millisecond
+
 // ScenarioRunner executes a sequence of actions defined in a scenario
 type ScenarioRunner struct {
+	id          string
 	contact_dir string
 	time_start  int64
 	time_end    int64
-	scenario    []map[string]string
+	scenario    *scenario.Scenario
 	host        *ahost.AbyssHost
 	out_f       *os.File
 
-	world_mtx sync.Mutex
-	world     *and.World
+	coord  *coord.Client // nil unless --coord was given; see resolvePeer/logEvent
+	n_peer int
+
+	// peerCache mirrors the coordinator's peer registry, kept current by
+	// watchPeers's WatchPeers stream; resolvePeer blocks on peerCacheCond
+	// instead of polling GetPeer. Unused in contact_dir mode.
+	peerCacheMu   sync.Mutex
+	peerCacheCond *sync.Cond
+	peerCache     map[string]coord.PeerInfo
+
+	clock eventlog.Clock
+	log_w *eventlog.Writer
+
+	netem_w *netem.Writer // nil unless --netem_out was given; see setLinkStep
+
+	rng *rand.Rand // seeded from scenario.Seed; see pickRandomPeer
+
+	// The following are all protected by world_mtx: they're read from the
+	// Run goroutine (random_peer selection, "assert") and written from both
+	// Run (knownPeers) and HandleEvents (joinedPeers, eventCounts).
+	world_mtx   sync.Mutex
+	world       *and.World
+	knownPeers  []string
+	joinedPeers map[string]uuid.UUID // peer id -> session id, for the open world
+	eventCounts map[string]int       // EventType -> times logged, for "assert"
 }
 
-// NewScenarioRunner creates a new ScenarioRunner with the given scenario and host
-func NewScenarioRunner(contact_dir string, time_start int64, duration int64, scenario []map[string]string, host *ahost.AbyssHost, output_path string) *ScenarioRunner {
+// NewScenarioRunner creates a new ScenarioRunner with the given scenario and host.
+// coordClient is nil when the legacy contact_dir/file-based mode is used.
+// netem_path is empty unless --netem_out was given.
+func NewScenarioRunner(id string, contact_dir string, time_start int64, duration int64, scen *scenario.Scenario, host *ahost.AbyssHost, output_path string, netem_path string, coordClient *coord.Client, n_peer int) *ScenarioRunner {
 	out_f, err := os.Create(output_path)
 	if err != nil {
 		log.Fatalf("Error reading scenario file: %v", err)
 	}
-	return &ScenarioRunner{
+
+	seed := scen.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+		log.Printf("scenario: no seed set, using %d for random_peer selection this run (not reproducible)", seed)
+	}
+
+	var netem_w *netem.Writer
+	if netem_path != "" {
+		netem_f, err := os.Create(netem_path)
+		if err != nil {
+			log.Fatalf("Error creating netem sidecar file: %v", err)
+		}
+		netem_w = netem.NewWriter(netem_f)
+	}
+
+	sr := &ScenarioRunner{
+		id:          id,
 		contact_dir: contact_dir,
 		time_start:  time_start,
 		time_end:    time_start + duration,
-		scenario:    scenario,
+		scenario:    scen,
 		host:        host,
 		out_f:       out_f,
+		log_w:       eventlog.NewWriter(out_f),
+		netem_w:     netem_w,
+		rng:         rand.New(rand.NewSource(seed)),
+		coord:       coordClient,
+		n_peer:      n_peer,
+		peerCache:   make(map[string]coord.PeerInfo),
+		joinedPeers: make(map[string]uuid.UUID),
+		eventCounts: make(map[string]int),
 	}
+	sr.peerCacheCond = sync.NewCond(&sr.peerCacheMu)
+	return sr
 }
 
-// Run executes the scenario by iterating over each step and waiting until the specified timestamp
-func (sr *ScenarioRunner) Run() error {
-	go sr.HandleEvents()
+// resolvePeer returns (rootCertificate, handshakeKeyCertificate, idHash) for
+// peer_id, either from contact_dir (legacy mode) or, when a coordinator is
+// configured, from peerCache — kept current by watchPeers's push-based
+// WatchPeers stream rather than polling GetPeer, since the peer may not have
+// registered yet, so a miss is not fatal until getPeerRetries*getPeerInterval
+// has passed with no sign of it.
+func (sr *ScenarioRunner) resolvePeer(peer_id string) (rc, hs, idHash string, err error) {
+	if sr.coord == nil {
+		rcBytes, err := os.ReadFile(path.Join(sr.contact_dir, peer_id+"_rc"))
+		if err != nil {
+			return "", "", "", err
+		}
+		hsBytes, err := os.ReadFile(path.Join(sr.contact_dir, peer_id+"_hs"))
+		if err != nil {
+			return "", "", "", err
+		}
+		idBytes, err := os.ReadFile(path.Join(sr.contact_dir, peer_id+"_id"))
+		if err != nil {
+			return "", "", "", err
+		}
+		return string(rcBytes), string(hsBytes), string(idBytes), nil
+	}
 
-	for i, step := range sr.scenario {
-		timeStr, ok := step["time"]
-		if !ok {
-			log.Printf("Warning: Step %d missing 'time' field, skipping", i)
-			continue
+	deadline := time.Now().Add(getPeerRetries * getPeerInterval)
+
+	sr.peerCacheMu.Lock()
+	defer sr.peerCacheMu.Unlock()
+	for {
+		if peer, ok := sr.peerCache[peer_id]; ok {
+			return peer.RootCertificate, peer.HandshakeKeyCertificate, peer.IDHash, nil
+		}
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return "", "", "", fmt.Errorf("coord: peer %s never registered after %v", peer_id, getPeerRetries*getPeerInterval)
 		}
+		sr.waitPeerCache(remaining)
+	}
+}
+
+// waitPeerCache blocks on peerCacheCond, which watchPeers broadcasts on
+// every registry update, until that happens or timeout elapses — whichever
+// comes first. Callers must hold sr.peerCacheMu.
+func (sr *ScenarioRunner) waitPeerCache(timeout time.Duration) {
+	// The timer takes peerCacheMu before broadcasting, so it cannot fire in
+	// the gap before Wait releases the lock and have its wakeup lost.
+	timer := time.AfterFunc(timeout, func() {
+		sr.peerCacheMu.Lock()
+		sr.peerCacheCond.Broadcast()
+		sr.peerCacheMu.Unlock()
+	})
+	defer timer.Stop()
+	sr.peerCacheCond.Wait()
+}
 
-		timestamp, err := strconv.ParseInt(timeStr, 10, 64)
+// watchPeers streams the coordinator's peer registry into peerCache via
+// WatchPeers, replacing what used to be resolvePeer's own per-call GetPeer
+// poll with one long-lived push feed; it returns once the stream ends, which
+// only happens if the coordinator connection is lost.
+func (sr *ScenarioRunner) watchPeers() {
+	stream, err := sr.coord.WatchPeers(context.Background(), &coord.WatchPeersRequest{})
+	if err != nil {
+		log.Printf("coord: WatchPeers: %v", err)
+		return
+	}
+	for {
+		peer, err := stream.Recv()
 		if err != nil {
-			log.Printf("Error: Step %d has invalid timestamp '%s': %v", i, timeStr, err)
-			continue
+			log.Printf("coord: WatchPeers stream ended: %v", err)
+			return
 		}
+		sr.peerCacheMu.Lock()
+		sr.peerCache[peer.ID] = *peer
+		sr.peerCacheCond.Broadcast()
+		sr.peerCacheMu.Unlock()
+	}
+}
 
-		target_timestamp := sr.time_start + timestamp
+// barrierStep blocks until every one of sr.n_peer participants has called it
+// with the same step label, keeping "join" actions synchronized across
+// peers instead of each one racing its own filesystem-poll retry loop.
+func (sr *ScenarioRunner) barrierStep(step string) error {
+	if sr.coord == nil || sr.n_peer <= 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), barrierWait)
+	defer cancel()
+	_, err := sr.coord.Barrier(ctx, &coord.BarrierRequest{Step: step, PeerID: sr.id, NPeers: sr.n_peer})
+	return err
+}
+
+// logEvent records a scenario event, ticking sr.clock so the record carries a
+// Lamport timestamp alongside its wall-clock time: cmd/logmerge uses that to
+// reconstruct one causally ordered stream across every peer in a run, which
+// unsynchronized wall clocks alone can't guarantee. worldSession is always
+// set; peerSession is uuid.Nil for "E"/"X" events, which have no peer.
+// In coordinator mode the record is additionally forwarded to the
+// coordinator's SubmitEvent RPC, so peers that don't share a filesystem
+// still produce one merged stream; the response's GlobalLamportSeq is then
+// folded back into sr.clock via Observe, so that round trip is also what
+// actually keeps every host's Lamport clock synchronized with the others'.
+//
+// Callers must hold world_mtx: logEvent also updates eventCounts, which
+// "assert" reads under the same lock.
+func (sr *ScenarioRunner) logEvent(eventType string, worldSession, peerSession uuid.UUID) {
+	sr.eventCounts[eventType]++
+
+	seq := sr.clock.Tick()
+	rec := eventlog.Record{
+		HostID:       sr.id,
+		WallMs:       time.Now().UnixMilli(),
+		LamportSeq:   seq,
+		EventType:    eventType,
+		WorldSession: worldSession.String(),
+	}
+	if peerSession != uuid.Nil {
+		rec.PeerSession = peerSession.String()
+	}
+	if err := sr.log_w.Write(rec); err != nil {
+		log.Printf("eventlog: write failed: %v", err)
+	}
+
+	if sr.coord == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), submitEventWait)
+	defer cancel()
+	resp, err := sr.coord.SubmitEvent(ctx, &coord.SubmitEventRequest{
+		Event: coord.Event{
+			HostID:       rec.HostID,
+			WallMs:       rec.WallMs,
+			LamportSeq:   rec.LamportSeq,
+			Type:         rec.EventType,
+			WorldSession: rec.WorldSession,
+			PeerSession:  rec.PeerSession,
+		},
+	})
+	if err != nil {
+		log.Printf("coord: SubmitEvent failed: %v", err)
+		return
+	}
+	sr.clock.Observe(resp.GlobalLamportSeq)
+}
+
+// Run executes the scenario's flattened action list in order, waiting until
+// each action's timestamp before dispatching it to the Step registered for
+// its Do name in stepRegistry.
+func (sr *ScenarioRunner) Run() error {
+	go sr.HandleEvents()
+	if sr.coord != nil {
+		go sr.watchPeers()
+	}
+
+	for i, action := range sr.scenario.Actions {
+		target_timestamp := sr.time_start + action.Time
 		if target_timestamp >= sr.time_end {
 			break
 		}
@@ -72,72 +285,13 @@ func (sr *ScenarioRunner) Run() error {
 			time.Sleep(waitDuration)
 		}
 
-		// Action
-		switch step["do"] {
-		case "add":
-
-			peer_id := step["id"]
-			rc, err := os.ReadFile(path.Join(sr.contact_dir, peer_id+"_rc"))
-			if err != nil {
-				log.Fatalf("unable to read file: %v", err)
-			}
-			hs, err := os.ReadFile(path.Join(sr.contact_dir, peer_id+"_hs"))
-			if err != nil {
-				log.Fatalf("unable to read file: %v", err)
-			}
-			sr.host.AppendKnownPeer(string(rc), string(hs))
-
-		case "dial":
-
-			peer_id := step["id"]
-			id_hash, err := os.ReadFile(path.Join(sr.contact_dir, peer_id+"_id"))
-			if err != nil {
-				log.Fatalf("unable to read file: %v", err)
-			}
-			sr.host.Dial(string(id_hash))
-
-		case "join":
-
-			peer_id := step["id"]
-			id_hash, err := os.ReadFile(path.Join(sr.contact_dir, peer_id+"_id"))
-			if err != nil {
-				log.Fatalf("unable to read file: %v", err)
-			}
-
-			sr.world_mtx.Lock()
-			if sr.world != nil {
-				sr.host.CloseWorld(sr.world) // This automatically frees world path
-				fmt.Fprintf(sr.out_f, "%d X %v\n", time.Now().UnixMilli(), sr.world.SessionID())
-			}
-			sr.world = nil
-			sr.world_mtx.Unlock()
-
-			for i := range 100 {
-				if i == 99 {
-					log.Println("Error: Failed to join. This is a failure.")
-					break
-				}
-
-				sr.world_mtx.Lock()
-				sr.world, err = sr.host.JoinWorld(string(id_hash), "/")
-				sr.world_mtx.Unlock()
-
-				if err == nil {
-					break
-				}
-				time.Sleep(time.Millisecond * 100)
-			}
-
-		case "open":
-
-			sr.world_mtx.Lock()
-			if sr.world != nil {
-				sr.host.CloseWorld(sr.world) // This automatically frees world path
-				fmt.Fprintf(sr.out_f, "%d X %v\n", time.Now().UnixMilli(), sr.world.SessionID())
-			}
-			sr.world = sr.host.OpenWorld("https://www.example.com")
-			sr.world_mtx.Unlock()
-
+		step, ok := stepRegistry[action.Do]
+		if !ok {
+			log.Printf("Warning: Step %d has unknown action %q, skipping", i, action.Do)
+			continue
+		}
+		if err := step.Do(sr, action, i); err != nil {
+			log.Printf("Error: step %d (%s) failed: %v", i, action.Do, err)
 		}
 	}
 
@@ -153,6 +307,57 @@ func (sr *ScenarioRunner) Run() error {
 	return nil
 }
 
+// resolvePeerTarget returns the peer id an action should act on: action.ID
+// literally, or a live pick from action.Peer's pool.
+func (sr *ScenarioRunner) resolvePeerTarget(action scenario.Action) (string, error) {
+	if action.ID != "" {
+		return action.ID, nil
+	}
+	if action.Peer == nil {
+		return "", fmt.Errorf("action %q requires \"id\" or \"random_peer\"", action.Do)
+	}
+	return sr.pickRandomPeer(action.Peer.From)
+}
+
+// pickRandomPeer draws a uniformly random peer id from the pool named by
+// from ("known", "joined", or "world"), using sr.rng so repeated runs with
+// the same scenario seed draw the same sequence.
+func (sr *ScenarioRunner) pickRandomPeer(from string) (string, error) {
+	sr.world_mtx.Lock()
+	defer sr.world_mtx.Unlock()
+
+	var pool []string
+	switch from {
+	case "known":
+		pool = sr.knownPeers
+	case "joined":
+		for peer_id := range sr.joinedPeers {
+			pool = append(pool, peer_id)
+		}
+		// Map iteration order is random; sort so the seeded draw is too.
+		sort.Strings(pool)
+	case "world":
+		pool = sr.scenario.Peers
+	default:
+		return "", fmt.Errorf("unknown random_peer.from %q", from)
+	}
+	if len(pool) == 0 {
+		return "", fmt.Errorf("no peers available for random_peer.from=%q", from)
+	}
+	return pool[sr.rng.Intn(len(pool))], nil
+}
+
+// memberIdentitiesLocked returns the current world's members as
+// ANDPeerSessionIdentitys, for WorldObjectAppend/WorldObjectDelete. Callers
+// must hold world_mtx.
+func (sr *ScenarioRunner) memberIdentitiesLocked() []and.ANDPeerSessionIdentity {
+	idents := make([]and.ANDPeerSessionIdentity, 0, len(sr.joinedPeers))
+	for peer_id, session_id := range sr.joinedPeers {
+		idents = append(idents, and.ANDPeerSessionIdentity{PeerID: peer_id, SessionID: session_id})
+	}
+	return idents
+}
+
 func (sr *ScenarioRunner) HandleEvents() {
 	event_ch := sr.host.GetEventCh()
 
@@ -169,7 +374,7 @@ func (sr *ScenarioRunner) HandleEvents() {
 
 			if sr.world != nil && sr.world.SessionID() == event.World.SessionID() {
 				sr.host.ExposeWorldForJoin(sr.world, "/") // this should not fail.
-				fmt.Fprintf(sr.out_f, "%d E %v\n", time.Now().UnixMilli(), event.World.SessionID())
+				sr.logEvent("E", event.World.SessionID(), uuid.Nil)
 			}
 
 		case *and.EANDSessionRequest:
@@ -181,13 +386,15 @@ func (sr *ScenarioRunner) HandleEvents() {
 		case *and.EANDSessionReady:
 
 			if sr.world != nil && sr.world.SessionID() == event.World.SessionID() {
-				fmt.Fprintf(sr.out_f, "%d J %v\n", time.Now().UnixMilli(), event.SessionID)
+				sr.joinedPeers[event.Peer.ID()] = event.SessionID
+				sr.logEvent("J", event.World.SessionID(), event.SessionID)
 			}
 
 		case *and.EANDSessionClose:
 
 			if sr.world != nil && sr.world.SessionID() == event.World.SessionID() {
-				fmt.Fprintf(sr.out_f, "%d L %v\n", time.Now().UnixMilli(), event.SessionID)
+				delete(sr.joinedPeers, event.Peer.ID())
+				sr.logEvent("L", event.World.SessionID(), event.SessionID)
 			}
 
 		case *and.EANDObjectAppend:
@@ -196,7 +403,8 @@ func (sr *ScenarioRunner) HandleEvents() {
 
 			if sr.world != nil && sr.world.SessionID() == event.World.SessionID() {
 				sr.world = nil
-				fmt.Fprintf(sr.out_f, "%d X %v\n", time.Now().UnixMilli(), event.World.SessionID())
+				clear(sr.joinedPeers)
+				sr.logEvent("X", event.World.SessionID(), uuid.Nil)
 			}
 			// case *ahost.EPeerConnected:
 			// 	fmt.Fprintf(sr.out_f, "%d Cn %v\n", time.Now().UnixMilli(), event.PeerID)
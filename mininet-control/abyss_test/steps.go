@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kadmila/Abyss-Browser/abyss_core/and"
+
+	"main/netem"
+	"main/scenario"
+)
+
+// Step is one registered scenario action. stepRegistry maps a scenario
+// action's Do name to its Step, so Run's interpreter never needs a growing
+// switch statement: a new action is added by defining a type and an entry
+// below, not by touching Run.
+type Step interface {
+	Do(sr *ScenarioRunner, action scenario.Action, stepIndex int) error
+}
+
+var stepRegistry = map[string]Step{
+	"add":            addStep{},
+	"dial":           dialStep{},
+	"join":           joinStep{},
+	"open":           openStep{},
+	"leave":          leaveStep{},
+	"publish_object": publishObjectStep{},
+	"delete_object":  deleteObjectStep{},
+	"set_link":       setLinkStep{},
+	"assert":         assertStep{},
+}
+
+// closeWorldLocked closes sr.world if one is open and logs the matching "X"
+// event. Callers must hold world_mtx.
+func (sr *ScenarioRunner) closeWorldLocked() {
+	if sr.world == nil {
+		return
+	}
+	sr.host.CloseWorld(sr.world) // This automatically frees world path
+	sr.logEvent("X", sr.world.SessionID(), uuid.Nil)
+	sr.world = nil
+	clear(sr.joinedPeers)
+}
+
+// addStep appends a peer to the host's known-peer list, learning its
+// certificates via resolvePeer.
+type addStep struct{}
+
+func (addStep) Do(sr *ScenarioRunner, action scenario.Action, stepIndex int) error {
+	peer_id, err := sr.resolvePeerTarget(action)
+	if err != nil {
+		return err
+	}
+	rc, hs, _, err := sr.resolvePeer(peer_id)
+	if err != nil {
+		log.Fatalf("unable to resolve peer %s: %v", peer_id, err)
+	}
+	sr.host.AppendKnownPeer(rc, hs)
+
+	sr.world_mtx.Lock()
+	sr.knownPeers = append(sr.knownPeers, peer_id)
+	sr.world_mtx.Unlock()
+	return nil
+}
+
+// dialStep opens a direct connection to a known peer.
+type dialStep struct{}
+
+func (dialStep) Do(sr *ScenarioRunner, action scenario.Action, stepIndex int) error {
+	peer_id, err := sr.resolvePeerTarget(action)
+	if err != nil {
+		return err
+	}
+	_, _, idHash, err := sr.resolvePeer(peer_id)
+	if err != nil {
+		log.Fatalf("unable to resolve peer %s: %v", peer_id, err)
+	}
+	sr.host.Dial(idHash)
+	return nil
+}
+
+// joinStep closes any currently open world and joins the one hosted by the
+// target peer, retrying briefly since the peer may not have opened it yet.
+type joinStep struct{}
+
+func (joinStep) Do(sr *ScenarioRunner, action scenario.Action, stepIndex int) error {
+	peer_id, err := sr.resolvePeerTarget(action)
+	if err != nil {
+		return err
+	}
+	_, _, idHash, err := sr.resolvePeer(peer_id)
+	if err != nil {
+		log.Fatalf("unable to resolve peer %s: %v", peer_id, err)
+	}
+
+	sr.world_mtx.Lock()
+	sr.closeWorldLocked()
+	sr.world_mtx.Unlock()
+
+	if err := sr.barrierStep(fmt.Sprintf("join-%d", stepIndex)); err != nil {
+		log.Printf("coord: barrier for step %d failed, joining without synchronization: %v", stepIndex, err)
+	}
+
+	for attempt := range 100 {
+		if attempt == 99 {
+			log.Println("Error: Failed to join. This is a failure.")
+			break
+		}
+
+		sr.world_mtx.Lock()
+		sr.world, err = sr.host.JoinWorld(idHash, "/")
+		sr.world_mtx.Unlock()
+
+		if err == nil {
+			break
+		}
+		time.Sleep(time.Millisecond * 100)
+	}
+	return nil
+}
+
+// openStep closes any currently open world and opens a fresh one.
+type openStep struct{}
+
+func (openStep) Do(sr *ScenarioRunner, action scenario.Action, stepIndex int) error {
+	sr.world_mtx.Lock()
+	defer sr.world_mtx.Unlock()
+	sr.closeWorldLocked()
+	sr.world = sr.host.OpenWorld("https://www.example.com")
+	return nil
+}
+
+// leaveStep closes the currently open world without opening a new one.
+type leaveStep struct{}
+
+func (leaveStep) Do(sr *ScenarioRunner, action scenario.Action, stepIndex int) error {
+	sr.world_mtx.Lock()
+	defer sr.world_mtx.Unlock()
+	sr.closeWorldLocked()
+	return nil
+}
+
+// publishObjectStep shares a new object with every peer in the open world.
+type publishObjectStep struct{}
+
+func (publishObjectStep) Do(sr *ScenarioRunner, action scenario.Action, stepIndex int) error {
+	if action.Object == nil {
+		return fmt.Errorf("publish_object: missing \"object\"")
+	}
+
+	sr.world_mtx.Lock()
+	defer sr.world_mtx.Unlock()
+	if sr.world == nil {
+		return fmt.Errorf("publish_object: no open world")
+	}
+
+	obj := and.ObjectInfo{ID: uuid.New(), Addr: action.Object.Addr, Transform: action.Object.Transform}
+	sr.host.WorldObjectAppend(sr.world, sr.memberIdentitiesLocked(), []and.ObjectInfo{obj})
+	return nil
+}
+
+// deleteObjectStep removes a previously published object from the open
+// world.
+type deleteObjectStep struct{}
+
+func (deleteObjectStep) Do(sr *ScenarioRunner, action scenario.Action, stepIndex int) error {
+	if action.ObjectID == "" {
+		return fmt.Errorf("delete_object: missing \"object_id\"")
+	}
+	id, err := uuid.Parse(action.ObjectID)
+	if err != nil {
+		return fmt.Errorf("delete_object: invalid \"object_id\" %q: %w", action.ObjectID, err)
+	}
+
+	sr.world_mtx.Lock()
+	defer sr.world_mtx.Unlock()
+	if sr.world == nil {
+		return fmt.Errorf("delete_object: no open world")
+	}
+
+	sr.host.WorldObjectDelete(sr.world, sr.memberIdentitiesLocked(), []uuid.UUID{id})
+	return nil
+}
+
+// setLinkStep appends a rate/loss/delay override to the netem sidecar file;
+// it doesn't touch the simulated network itself, an external tc/netem
+// controller watching that file does.
+type setLinkStep struct{}
+
+func (setLinkStep) Do(sr *ScenarioRunner, action scenario.Action, stepIndex int) error {
+	if action.Link == nil {
+		return fmt.Errorf("set_link: missing \"link\"")
+	}
+	if sr.netem_w == nil {
+		return fmt.Errorf("set_link: no --netem_out configured")
+	}
+	return sr.netem_w.Write(netem.LinkUpdate{
+		WallMs:   time.Now().UnixMilli(),
+		HostID:   sr.id,
+		TargetID: action.Link.TargetID,
+		RateKbps: action.Link.RateKbps,
+		LossPct:  action.Link.LossPct,
+		DelayMs:  action.Link.DelayMs,
+	})
+}
+
+// assertStep checks expected run state and fatally aborts the run if it
+// doesn't hold, so a misbehaving scenario fails loudly instead of finishing
+// having silently not done what it was meant to test.
+type assertStep struct{}
+
+func (assertStep) Do(sr *ScenarioRunner, action scenario.Action, stepIndex int) error {
+	if action.Assert == nil {
+		return fmt.Errorf("assert: missing \"assert\"")
+	}
+
+	sr.world_mtx.Lock()
+	defer sr.world_mtx.Unlock()
+
+	if want := action.Assert.WorldMembers; want != nil {
+		if got := len(sr.joinedPeers); got != *want {
+			log.Fatalf("assert failed at step %d: world_members = %d, want %d", stepIndex, got, *want)
+		}
+	}
+	if ec := action.Assert.EventCount; ec != nil {
+		if got := sr.eventCounts[ec.Type]; got != ec.Count {
+			log.Fatalf("assert failed at step %d: event_count[%s] = %d, want %d", stepIndex, ec.Type, got, ec.Count)
+		}
+	}
+	return nil
+}
@@ -1,90 +1,72 @@
-package main
-
-import (
-	"encoding/binary"
-	"fmt"
-	"io"
-	"net/netip"
-	"os"
-	"path/filepath"
-)
-
-func main() {
-	if len(os.Args) < 2 {
-		fmt.Println("Usage: program <directory>")
-		return
-	}
-
-	dirPath := os.Args[1]
-
-	// Read all files in the directory
-	files, err := os.ReadDir(dirPath)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error reading directory: %v\n", err)
-		os.Exit(1)
-	}
-
-	var filePaths []string
-	for _, file := range files {
-		// Skip directories, only include files
-		if file.IsDir() {
-			continue
-		}
-		filePaths = append(filePaths, filepath.Join(dirPath, file.Name()))
-	}
-
-	dictionary := make(map[uint32]bool)
-
-	// Process each file
-	for _, filePath := range filePaths {
-		err := processFile(filePath, dictionary)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error processing %s: %v\n", filePath, err)
-			continue
-		}
-	}
-
-	for address, _ := range dictionary {
-		var bytes [4]byte
-		binary.NativeEndian.PutUint32(bytes[:], address)
-		addr := netip.AddrFrom4(bytes)
-		fmt.Printf("%s\n", addr.String())
-	}
-	fmt.Printf("Dictionary has %d unique address.\n", len(dictionary))
-}
-
-func processFile(filename string, dictionary map[uint32]bool) error {
-	file, err := os.Open(filename)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	const structSize = 20
-	buffer := make([]byte, structSize*1024*1024) // 10MB buffer
-
-	for {
-		n, err := file.Read(buffer)
-		if n == 0 {
-			break
-		}
-
-		count := n / structSize
-		for i := range count {
-			offset := i * structSize
-
-			dstAddr := binary.NativeEndian.Uint32(buffer[offset+12:])
-			srcAddr := binary.NativeEndian.Uint32(buffer[offset+16:])
-			dictionary[dstAddr] = false
-			dictionary[srcAddr] = false
-		}
-
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return err
-		}
-	}
-	return nil
-}
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/netip"
+	"os"
+	"runtime"
+
+	"main/pkg/ingest"
+)
+
+func main() {
+	var workers int
+	var endianFlag string
+	flag.IntVar(&workers, "workers", runtime.NumCPU(), "number of goroutines to shard file ingestion across")
+	flag.StringVar(&endianFlag, "endian", "native", "byte order of the captured record files: le, be, or native")
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		fmt.Println("Usage: program [--workers=N] [--endian=le|be|native] <directory>")
+		return
+	}
+	dirPath := flag.Arg(0)
+
+	endian, err := ingest.ParseEndian(endianFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	// One address set per worker, merged once ingestion completes, so no
+	// lock is held on the hot path. paths is listed once and shared between
+	// ResolveWorkerCount and ProcessPaths so the two can't disagree on the
+	// worker count; ResolveWorkerCount clamps a <=0 --workers to
+	// runtime.NumCPU() internally.
+	paths, err := ingest.ListDir(dirPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading directory: %v\n", err)
+		os.Exit(1)
+	}
+	workerCount := ingest.ResolveWorkerCount(paths, workers)
+	local := make([]map[uint32]bool, workerCount)
+	for i := range local {
+		local[i] = make(map[uint32]bool)
+	}
+
+	err = ingest.ProcessPaths(paths, ingest.Options{Workers: workers, Endian: endian}, func(worker int, rec ingest.Record) {
+		local[worker][rec.DstAddr] = false
+		local[worker][rec.SrcAddr] = false
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading directory: %v\n", err)
+		os.Exit(1)
+	}
+
+	dictionary := make(map[uint32]bool)
+	for _, m := range local {
+		for addr := range m {
+			dictionary[addr] = false
+		}
+	}
+
+	order := endian.ByteOrder()
+	for address := range dictionary {
+		var bytes [4]byte
+		order.PutUint32(bytes[:], address)
+		addr := netip.AddrFrom4(bytes)
+		fmt.Printf("%s\n", addr.String())
+	}
+	fmt.Printf("Dictionary has %d unique address.\n", len(dictionary))
+}
@@ -0,0 +1,134 @@
+// Package estimator computes robust latency summaries from RTT samples.
+// Sigma-clipping is sensitive to the very outliers it tries to remove and
+// biased on skewed, one-sided RTT distributions; MedianMAD and Huber trade
+// some efficiency under a clean Gaussian for resilience to that skew.
+package estimator
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// Estimate is the per-link record an Estimator produces. Min/P25/Median/P75
+// /Max/Mean/StdDev/MAD are all computed over the samples that survived
+// outlier rejection (NKept of the original N), except that Huber reports
+// its location estimate as Mean and its MAD-based scale of the full sample
+// as StdDev.
+type Estimate struct {
+	N      int     `json:"n"`
+	NKept  int     `json:"n_kept"`
+	Min    float64 `json:"min"`
+	P25    float64 `json:"p25"`
+	Median float64 `json:"median"`
+	P75    float64 `json:"p75"`
+	Max    float64 `json:"max"`
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"stddev"`
+	MAD    float64 `json:"mad"`
+}
+
+// Estimator reduces a set of RTT samples (milliseconds) to an Estimate.
+type Estimator interface {
+	Estimate(samples []float32) (Estimate, error)
+}
+
+// errEmpty is returned whenever every sample has been rejected, or there
+// were none to begin with.
+var errEmpty = errors.New("estimator: no samples survived")
+
+// summarize builds the common Estimate fields from the samples that
+// survived an Estimator's rejection pass. n is the original sample count
+// before rejection.
+func summarize(n int, kept []float32) (Estimate, error) {
+	if len(kept) == 0 {
+		return Estimate{}, errEmpty
+	}
+
+	sorted := append([]float32(nil), kept...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	mean := meanOf(sorted)
+	median := quantile(sorted, 0.5)
+
+	return Estimate{
+		N:      n,
+		NKept:  len(sorted),
+		Min:    float64(sorted[0]),
+		P25:    quantile(sorted, 0.25),
+		Median: median,
+		P75:    quantile(sorted, 0.75),
+		Max:    float64(sorted[len(sorted)-1]),
+		Mean:   mean,
+		StdDev: stddevOf(sorted, mean),
+		MAD:    madAround(sorted, median),
+	}, nil
+}
+
+func meanOf(data []float32) float64 {
+	var sum float64
+	for _, v := range data {
+		sum += float64(v)
+	}
+	return sum / float64(len(data))
+}
+
+func stddevOf(data []float32, mean float64) float64 {
+	var sum float64
+	for _, v := range data {
+		diff := float64(v) - mean
+		sum += diff * diff
+	}
+	return math.Sqrt(sum / float64(len(data)))
+}
+
+// quantile linearly interpolates the q-th quantile (0..1) of sorted, which
+// must already be ascending.
+func quantile(sorted []float32, q float64) float64 {
+	if len(sorted) == 1 {
+		return float64(sorted[0])
+	}
+	pos := q * float64(len(sorted)-1)
+	lo := int(math.Floor(pos))
+	hi := int(math.Ceil(pos))
+	if lo == hi {
+		return float64(sorted[lo])
+	}
+	frac := pos - float64(lo)
+	return float64(sorted[lo])*(1-frac) + float64(sorted[hi])*frac
+}
+
+// madAround returns median(|x - center|) for sorted, which need not be
+// sorted by absolute deviation (it is re-sorted internally).
+func madAround(sorted []float32, center float64) float64 {
+	devs := make([]float64, len(sorted))
+	for i, v := range sorted {
+		devs[i] = math.Abs(float64(v) - center)
+	}
+	sort.Float64s(devs)
+	return quantileF64(devs, 0.5)
+}
+
+func quantileF64(sorted []float64, q float64) float64 {
+	if len(sorted) == 1 {
+		return sorted[0]
+	}
+	pos := q * float64(len(sorted)-1)
+	lo := int(math.Floor(pos))
+	hi := int(math.Ceil(pos))
+	if lo == hi {
+		return sorted[lo]
+	}
+	frac := pos - float64(lo)
+	return sorted[lo]*(1-frac) + sorted[hi]*frac
+}
+
+func clamp(u, lo, hi float64) float64 {
+	if u < lo {
+		return lo
+	}
+	if u > hi {
+		return hi
+	}
+	return u
+}
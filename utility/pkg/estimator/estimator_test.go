@@ -0,0 +1,136 @@
+package estimator
+
+import (
+	"math"
+	"testing"
+)
+
+// skewedSamples is a one-sided RTT-like distribution: a tight cluster around
+// 10ms with a handful of large outliers on the high side only, the shape
+// SigmaClip is documented to be biased on.
+func skewedSamples() []float32 {
+	samples := []float32{
+		9.8, 9.9, 10.0, 10.0, 10.1, 10.1, 10.2, 9.9, 10.0, 10.1,
+	}
+	return append(samples, 500, 600, 700)
+}
+
+func TestMedianMAD_RejectsOutliersOnSkewedSample(t *testing.T) {
+	est := MedianMAD{K: 3, MaxIter: 5}
+	got, err := est.Estimate(skewedSamples())
+	if err != nil {
+		t.Fatalf("Estimate: %v", err)
+	}
+	if got.N != 13 {
+		t.Errorf("N = %d, want 13", got.N)
+	}
+	if got.NKept != 10 {
+		t.Errorf("NKept = %d, want 10 (the three outliers rejected)", got.NKept)
+	}
+	if math.Abs(got.Median-10.0) > 0.2 {
+		t.Errorf("Median = %f, want ~10.0", got.Median)
+	}
+}
+
+func TestMedianMAD_AllSamplesEqual(t *testing.T) {
+	// scale == 0 (every sample at the median): the degenerate path in
+	// Estimate must keep every sample rather than rejecting everything.
+	est := MedianMAD{K: 3, MaxIter: 5}
+	got, err := est.Estimate([]float32{5, 5, 5, 5})
+	if err != nil {
+		t.Fatalf("Estimate: %v", err)
+	}
+	if got.NKept != 4 {
+		t.Errorf("NKept = %d, want 4", got.NKept)
+	}
+	if got.Median != 5 {
+		t.Errorf("Median = %f, want 5", got.Median)
+	}
+}
+
+func TestMedianMAD_EmptyInput(t *testing.T) {
+	_, err := (MedianMAD{K: 3, MaxIter: 5}).Estimate(nil)
+	if err == nil {
+		t.Fatal("Estimate(nil): want error, got nil")
+	}
+}
+
+func TestHuber_ConvergesOnSkewedSample(t *testing.T) {
+	est := Huber{C: 1.345, MaxIter: 10}
+	got, err := est.Estimate(skewedSamples())
+	if err != nil {
+		t.Fatalf("Estimate: %v", err)
+	}
+	if got.N != 13 {
+		t.Errorf("N = %d, want 13", got.N)
+	}
+	// The Huber location should track the 10ms cluster, not be dragged
+	// toward the 500-700ms outliers the way a plain mean would be.
+	if math.Abs(got.Mean-10.0) > 0.5 {
+		t.Errorf("Mean (Huber location) = %f, want ~10.0", got.Mean)
+	}
+	if got.NKept < 9 || got.NKept > 10 {
+		t.Errorf("NKept = %d, want 9 or 10 (the three 500-700ms outliers rejected, plus maybe one borderline cluster point)", got.NKept)
+	}
+}
+
+func TestHuber_SigmaZeroKeepsEverySample(t *testing.T) {
+	// Every sample at the median means the MAD-based sigma is 0; Estimate
+	// must skip the iteration (which would divide by zero) and keep every
+	// sample rather than rejecting them all.
+	est := Huber{C: 1.345, MaxIter: 10}
+	got, err := est.Estimate([]float32{7, 7, 7})
+	if err != nil {
+		t.Fatalf("Estimate: %v", err)
+	}
+	if got.NKept != 3 {
+		t.Errorf("NKept = %d, want 3", got.NKept)
+	}
+	if got.Mean != 7 {
+		t.Errorf("Mean = %f, want 7 (median, since the iteration never ran)", got.Mean)
+	}
+	if got.StdDev != 0 {
+		t.Errorf("StdDev = %f, want 0", got.StdDev)
+	}
+}
+
+func TestHuber_ZeroCStopsOnWeightSumZero(t *testing.T) {
+	// C=0 clamps every nonzero u to 0, so every sample off the median gets
+	// weight 0; weightSum==0 on the first iteration must break out of the
+	// loop rather than divide by zero. mu is then left at the initial
+	// median, which (for this sample) no value matches exactly, so nothing
+	// survives the C*sigma keep filter either - documenting the degenerate
+	// behavior rather than hanging or panicking.
+	est := Huber{C: 0, MaxIter: 10}
+	_, err := est.Estimate([]float32{1, 2, 3, 100})
+	if err == nil {
+		t.Fatal("Estimate: want errEmpty (no sample equals the unmoved median), got nil error")
+	}
+}
+
+func TestSigmaClip_BiasedByOutliersOnSkewedSample(t *testing.T) {
+	// Documents the known bias SigmaClip.K=3 has on this one-sided sample:
+	// the outliers inflate stddev enough that they survive rejection,
+	// unlike MedianMAD/Huber above on the same data.
+	est := SigmaClip{K: 3, MaxIter: 5}
+	got, err := est.Estimate(skewedSamples())
+	if err != nil {
+		t.Fatalf("Estimate: %v", err)
+	}
+	if got.NKept != 13 {
+		t.Errorf("NKept = %d, want 13 (outliers survive sigma-clipping here)", got.NKept)
+	}
+}
+
+func TestEstimators_EmptyInputAllError(t *testing.T) {
+	estimators := map[string]Estimator{
+		"sigma":  SigmaClip{K: 3, MaxIter: 5},
+		"median": MedianMAD{K: 3, MaxIter: 5},
+		"huber":  Huber{C: 1.345, MaxIter: 5},
+	}
+	for name, est := range estimators {
+		if _, err := est.Estimate(nil); err == nil {
+			t.Errorf("%s.Estimate(nil): want error, got nil", name)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package estimator
+
+import (
+	"math"
+	"sort"
+)
+
+// Huber computes a Huber M-estimate of location: it iteratively re-weights
+// each sample by psi_C((x-mu)/sigma)/((x-mu)/sigma), clamping the influence
+// of any sample more than C standard deviations from the current estimate,
+// and takes the resulting weighted mean as the next mu. sigma is the
+// (fixed) MAD-based scale estimate of the full sample, so it is itself
+// robust to the outliers mu is being protected from. C is used as given,
+// with no default substituted for zero; C = 1.345 gives 95% efficiency
+// relative to the plain mean under a Gaussian.
+type Huber struct {
+	C       float64
+	MaxIter int
+}
+
+func (e Huber) Estimate(samples []float32) (Estimate, error) {
+	n := len(samples)
+	if n == 0 {
+		return Estimate{}, errEmpty
+	}
+
+	sorted := append([]float32(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	sigma := 1.4826 * madAround(sorted, quantile(sorted, 0.5))
+
+	mu := quantile(sorted, 0.5)
+	if sigma > 0 {
+		for iter := 0; iter < e.MaxIter; iter++ {
+			var weightedSum, weightSum float64
+			for _, v := range samples {
+				x := float64(v)
+				u := (x - mu) / sigma
+				w := 1.0
+				if u != 0 {
+					w = clamp(u, -e.C, e.C) / u
+				}
+				weightedSum += w * x
+				weightSum += w
+			}
+			if weightSum == 0 {
+				break
+			}
+			next := weightedSum / weightSum
+			converged := math.Abs(next-mu) < 1e-9
+			mu = next
+			if converged {
+				break
+			}
+		}
+	}
+
+	var kept []float32
+	for _, v := range samples {
+		if sigma == 0 || math.Abs(float64(v)-mu) <= e.C*sigma {
+			kept = append(kept, v)
+		}
+	}
+
+	est, err := summarize(n, kept)
+	if err != nil {
+		return Estimate{}, err
+	}
+	// Report the Huber location/scale rather than the plain mean/stddev of
+	// the kept set, since those are what the iteration actually solved for.
+	est.Mean = mu
+	est.StdDev = sigma
+	return est, nil
+}
@@ -0,0 +1,55 @@
+package estimator
+
+import (
+	"math"
+	"sort"
+)
+
+// MedianMAD iteratively rejects samples more than K*1.4826*MAD from the
+// median, recomputing both from the surviving set, until the set stops
+// shrinking or MaxIter is reached. 1.4826 makes the MAD a consistent
+// estimator of the standard deviation under a Gaussian, so K plays the same
+// role as SigmaClip's K while being far less sensitive to the outliers
+// themselves.
+type MedianMAD struct {
+	K       float64
+	MaxIter int
+}
+
+func (e MedianMAD) Estimate(samples []float32) (Estimate, error) {
+	n := len(samples)
+	data := append([]float32(nil), samples...)
+
+	for iter := 0; iter < e.MaxIter; iter++ {
+		if len(data) == 0 {
+			return Estimate{}, errEmpty
+		}
+
+		sorted := append([]float32(nil), data...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		median := quantile(sorted, 0.5)
+		scale := 1.4826 * madAround(sorted, median)
+
+		var filtered []float32
+		if scale == 0 {
+			// Every sample already sits at the median; nothing to reject.
+			filtered = data
+		} else {
+			threshold := e.K * scale
+			filtered = make([]float32, 0, len(data))
+			for _, v := range data {
+				if math.Abs(float64(v)-median) <= threshold {
+					filtered = append(filtered, v)
+				}
+			}
+		}
+
+		converged := len(filtered) == len(data)
+		data = filtered
+		if converged {
+			break
+		}
+	}
+
+	return summarize(n, data)
+}
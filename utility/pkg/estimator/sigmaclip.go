@@ -0,0 +1,44 @@
+package estimator
+
+import "math"
+
+// SigmaClip iteratively rejects samples more than K standard deviations
+// from the mean, recomputing both from the surviving set, until the set
+// stops shrinking or MaxIter is reached. This is the estimator the latency
+// processor used before MedianMAD/Huber were added; it is biased on
+// one-sided RTT distributions because the very outliers it is trying to
+// reject inflate the stddev used to reject them.
+type SigmaClip struct {
+	K       float64
+	MaxIter int
+}
+
+func (e SigmaClip) Estimate(samples []float32) (Estimate, error) {
+	n := len(samples)
+	data := append([]float32(nil), samples...)
+
+	for iter := 0; iter < e.MaxIter; iter++ {
+		if len(data) == 0 {
+			return Estimate{}, errEmpty
+		}
+
+		mean := meanOf(data)
+		stddev := stddevOf(data, mean)
+		threshold := e.K * stddev
+
+		filtered := make([]float32, 0, len(data))
+		for _, v := range data {
+			if math.Abs(float64(v)-mean) <= threshold {
+				filtered = append(filtered, v)
+			}
+		}
+
+		converged := len(filtered) == len(data)
+		data = filtered
+		if converged {
+			break
+		}
+	}
+
+	return summarize(n, data)
+}
@@ -0,0 +1,96 @@
+package geolink
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// expected header columns for the flattened CSV format this loader accepts.
+// Both a MaxMind GeoLite2-City export (joined on geoname_id ahead of time)
+// and an IPinfo "lite" export can be reshaped into this layout with a single
+// join/rename pass; we intentionally don't special-case either vendor here.
+var csvColumns = []string{"network", "city", "country", "latitude", "longitude"}
+
+const csvASNColumn = "asn"
+
+// LoadCSV reads a geolocation CSV from path and returns a Trie populated with
+// one entry per IPv4 row; rows with an IPv6 network are parsed but not
+// inserted (see Trie.Insert). The file must have a header row naming at least
+// "network" (a CIDR such as "203.0.113.0/24"), "city", "country",
+// "latitude" and "longitude"; an optional "asn" column is recorded on each
+// row's Location for informational output (see Node.ASN) but, since it's
+// only ever known for addresses that already resolve, isn't used as a
+// fallback for addresses the Trie has no entry for at all — see
+// Aggregator.resolve.
+func LoadCSV(path string) (*Trie, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.ReuseRecord = true
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("geolink: reading header: %w", err)
+	}
+	col := make(map[string]int, len(header))
+	for i, name := range header {
+		col[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, want := range csvColumns {
+		if _, ok := col[want]; !ok {
+			return nil, fmt.Errorf("geolink: csv missing required column %q", want)
+		}
+	}
+	asnIdx, hasASN := col[csvASNColumn]
+
+	t := NewTrie()
+	line := 1
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			return nil, fmt.Errorf("geolink: line %d: %w", line, err)
+		}
+
+		prefix, err := netip.ParsePrefix(record[col["network"]])
+		if err != nil {
+			return nil, fmt.Errorf("geolink: line %d: invalid network %q: %w", line, record[col["network"]], err)
+		}
+		lat, err := strconv.ParseFloat(record[col["latitude"]], 64)
+		if err != nil {
+			return nil, fmt.Errorf("geolink: line %d: invalid latitude: %w", line, err)
+		}
+		lon, err := strconv.ParseFloat(record[col["longitude"]], 64)
+		if err != nil {
+			return nil, fmt.Errorf("geolink: line %d: invalid longitude: %w", line, err)
+		}
+
+		loc := Location{
+			City:    record[col["city"]],
+			Country: record[col["country"]],
+			Lat:     lat,
+			Lon:     lon,
+		}
+		if hasASN {
+			if asn, err := strconv.ParseUint(strings.TrimPrefix(record[asnIdx], "AS"), 10, 32); err == nil {
+				loc.ASN = uint32(asn)
+			}
+		}
+
+		t.Insert(prefix, loc)
+	}
+
+	return t, nil
+}
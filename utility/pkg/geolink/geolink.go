@@ -0,0 +1,23 @@
+// Package geolink resolves IP addresses to approximate geographic locations
+// and aggregates RTT samples between resolved locations into a link topology.
+package geolink
+
+import "net/netip"
+
+// Location is the geographic and network identity resolved for an IP address.
+type Location struct {
+	City    string
+	Country string
+	Lat     float64
+	Lon     float64
+	ASN     uint32
+}
+
+// Resolver maps an IP address to the Location it belongs to. Implementations
+// may be backed by different offline databases (MaxMind GeoLite2, IPinfo
+// lite, in-house tables, ...); callers should only depend on this interface.
+type Resolver interface {
+	// Lookup returns the Location containing ip, or false if no entry in the
+	// underlying database covers it.
+	Lookup(ip netip.Addr) (Location, bool)
+}
@@ -0,0 +1,136 @@
+package geolink
+
+import (
+	"fmt"
+	"net/netip"
+
+	"main/pkg/estimator"
+)
+
+// Node is a single point in the emitted topology: either a resolved city or,
+// for addresses with no geo entry, a /24 fallback bucket.
+type Node struct {
+	ID      string  `json:"id"`
+	City    string  `json:"city"`
+	Country string  `json:"country,omitempty"`
+	Lat     float64 `json:"lat,omitempty"`
+	Lon     float64 `json:"lon,omitempty"`
+	ASN     uint32  `json:"asn,omitempty"`
+	Bucket  bool    `json:"bucket,omitempty"`
+}
+
+// Edge is a link between two Nodes with aggregated latency statistics
+// produced by an Estimator over every RTT sample observed between any pair
+// of addresses that resolved to those two nodes.
+type Edge struct {
+	A     string             `json:"a"`
+	B     string             `json:"b"`
+	Stats estimator.Estimate `json:"stats"`
+}
+
+// Topology is the JSON document handed off to the mininet2 simulator.
+type Topology struct {
+	Nodes []Node `json:"nodes"`
+	Edges []Edge `json:"edges"`
+}
+
+// EstimateFunc computes a link's latency statistics for a set of RTT
+// samples. utility/stats supplies this, backed by whichever
+// estimator.Estimator the user selected, so geolink stays agnostic of the
+// estimation method in use.
+type EstimateFunc func(samples []float32) (estimator.Estimate, error)
+
+// linkKey identifies an unordered pair of node IDs.
+type linkKey struct{ a, b string }
+
+func newLinkKey(a, b string) linkKey {
+	if a > b {
+		a, b = b, a
+	}
+	return linkKey{a, b}
+}
+
+// Aggregator resolves (srcAddr, dstAddr) RTT samples to city pairs via a
+// Resolver and groups them for later estimation.
+type Aggregator struct {
+	resolver Resolver
+	nodes    map[string]Node
+	samples  map[linkKey][]float32
+}
+
+// NewAggregator returns an Aggregator that resolves addresses through r.
+func NewAggregator(r Resolver) *Aggregator {
+	return &Aggregator{
+		resolver: r,
+		nodes:    make(map[string]Node),
+		samples:  make(map[linkKey][]float32),
+	}
+}
+
+// AddSamples resolves src and dst to nodes (falling back to a /24 bucket
+// when the Resolver has no entry) and appends rtts to the unordered
+// city-pair's sample set.
+func (a *Aggregator) AddSamples(src, dst netip.Addr, rtts ...float32) {
+	srcNode := a.resolve(src)
+	dstNode := a.resolve(dst)
+
+	if _, ok := a.nodes[srcNode.ID]; !ok {
+		a.nodes[srcNode.ID] = srcNode
+	}
+	if _, ok := a.nodes[dstNode.ID]; !ok {
+		a.nodes[dstNode.ID] = dstNode
+	}
+
+	key := newLinkKey(srcNode.ID, dstNode.ID)
+	a.samples[key] = append(a.samples[key], rtts...)
+}
+
+// resolve looks addr up in the Resolver. On a miss it buckets the address by
+// /24: the CSV's optional "asn" column (see LoadCSV) only ever attaches to
+// addresses the Resolver does find, so there is nothing to bucket an
+// unresolved address by except the address itself.
+func (a *Aggregator) resolve(addr netip.Addr) Node {
+	if loc, ok := a.resolver.Lookup(addr); ok {
+		return Node{
+			ID:      fmt.Sprintf("%s,%s", loc.City, loc.Country),
+			City:    loc.City,
+			Country: loc.Country,
+			Lat:     loc.Lat,
+			Lon:     loc.Lon,
+			ASN:     loc.ASN,
+		}
+	}
+
+	if addr.Is4() {
+		bits := addr.As4()
+		slash24 := netip.PrefixFrom(netip.AddrFrom4([4]byte{bits[0], bits[1], bits[2], 0}), 24)
+		id := slash24.String()
+		return Node{ID: id, City: fmt.Sprintf("unresolved %s", id), Bucket: true}
+	}
+
+	id := addr.String()
+	return Node{ID: id, City: fmt.Sprintf("unresolved %s", id), Bucket: true}
+}
+
+// Build runs estimate over every grouped city pair and returns the resulting
+// Topology. Pairs for which estimate returns an error are skipped.
+func (a *Aggregator) Build(estimate EstimateFunc) Topology {
+	topo := Topology{Nodes: make([]Node, 0, len(a.nodes))}
+	for _, n := range a.nodes {
+		topo.Nodes = append(topo.Nodes, n)
+	}
+
+	for key, samples := range a.samples {
+		stats, err := estimate(samples)
+		if err != nil {
+			continue
+		}
+		topo.Edges = append(topo.Edges, Edge{
+			A:     key.a,
+			B:     key.b,
+			Stats: stats,
+		})
+	}
+
+	return topo
+}
@@ -0,0 +1,81 @@
+package geolink
+
+import "net/netip"
+
+// trieNode is a single node of a binary (bitwise) prefix trie over IPv4
+// addresses. Each edge consumes one bit of the address, most significant
+// bit first. A node carries a Location when a CIDR entry terminates there;
+// lookups keep the most specific (longest-prefix) match seen on the walk.
+type trieNode struct {
+	children [2]*trieNode
+	loc      Location
+	has      bool
+}
+
+// Trie is an in-memory IPv4 prefix trie mapping CIDR blocks to Location
+// records. It implements Resolver. The zero value is ready to use.
+type Trie struct {
+	root trieNode
+}
+
+// NewTrie returns an empty Trie.
+func NewTrie() *Trie {
+	return &Trie{}
+}
+
+// Insert adds a mapping from the CIDR prefix to loc. Later inserts of a more
+// specific prefix take precedence over earlier, broader ones at lookup time.
+func (t *Trie) Insert(prefix netip.Prefix, loc Location) {
+	prefix = prefix.Masked()
+	addr := prefix.Addr()
+	if !addr.Is4() {
+		return
+	}
+	bits := addr.As4()
+	key := uint32(bits[0])<<24 | uint32(bits[1])<<16 | uint32(bits[2])<<8 | uint32(bits[3])
+
+	node := &t.root
+	for i := 0; i < prefix.Bits(); i++ {
+		bit := (key >> (31 - i)) & 1
+		child := node.children[bit]
+		if child == nil {
+			child = &trieNode{}
+			node.children[bit] = child
+		}
+		node = child
+	}
+	node.loc = loc
+	node.has = true
+}
+
+// Lookup implements Resolver by walking the trie bit-by-bit and returning the
+// Location of the longest matching prefix.
+func (t *Trie) Lookup(ip netip.Addr) (Location, bool) {
+	ip4 := ip
+	if ip.Is4In6() {
+		ip4 = ip.Unmap()
+	}
+	if !ip4.Is4() {
+		return Location{}, false
+	}
+	bits := ip4.As4()
+	key := uint32(bits[0])<<24 | uint32(bits[1])<<16 | uint32(bits[2])<<8 | uint32(bits[3])
+
+	node := &t.root
+	best := Location{}
+	found := false
+	if node.has {
+		best, found = node.loc, true
+	}
+	for i := 0; i < 32; i++ {
+		bit := (key >> (31 - i)) & 1
+		node = node.children[bit]
+		if node == nil {
+			break
+		}
+		if node.has {
+			best, found = node.loc, true
+		}
+	}
+	return best, found
+}
@@ -0,0 +1,146 @@
+package ingest
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sync"
+)
+
+// SpillAggregator groups float32 samples by an arbitrary uint64 key (e.g. a
+// PackKey'd address pair), spilling a key's samples to a scratch file once
+// they reach Threshold entries. This lets total ingested volume exceed RAM
+// even though any single link's in-memory slice does not grow unbounded.
+// Safe for concurrent use.
+type SpillAggregator struct {
+	// Threshold is the number of in-memory samples for a key that triggers
+	// a flush to disk. Zero disables spilling (samples only ever live in
+	// memory, matching the plain map[uint64][]float32 this replaces).
+	Threshold int
+	// Dir is the directory scratch files are created in; os.TempDir() is
+	// used when empty.
+	Dir string
+
+	mu      sync.Mutex
+	mem     map[uint64][]float32
+	spilled map[uint64]*os.File
+	counts  map[uint64]int
+}
+
+// NewSpillAggregator returns a ready-to-use SpillAggregator.
+func NewSpillAggregator(threshold int, dir string) *SpillAggregator {
+	return &SpillAggregator{
+		Threshold: threshold,
+		Dir:       dir,
+		mem:       make(map[uint64][]float32),
+		spilled:   make(map[uint64]*os.File),
+		counts:    make(map[uint64]int),
+	}
+}
+
+// Add appends samples to key's set, spilling to disk once the in-memory
+// set reaches Threshold.
+func (a *SpillAggregator) Add(key uint64, samples ...float32) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.mem[key] = append(a.mem[key], samples...)
+	a.counts[key] += len(samples)
+	if a.Threshold > 0 && len(a.mem[key]) >= a.Threshold {
+		return a.flushLocked(key)
+	}
+	return nil
+}
+
+func (a *SpillAggregator) flushLocked(key uint64) error {
+	f, ok := a.spilled[key]
+	if !ok {
+		var err error
+		f, err = os.CreateTemp(a.Dir, fmt.Sprintf("ingest-%016x-*.f32", key))
+		if err != nil {
+			return err
+		}
+		a.spilled[key] = f
+	}
+
+	pending := a.mem[key]
+	buf := make([]byte, 4*len(pending))
+	for i, v := range pending {
+		binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(v))
+	}
+	if _, err := f.Write(buf); err != nil {
+		return err
+	}
+	a.mem[key] = pending[:0]
+	return nil
+}
+
+// Keys returns every key Add has been called with.
+func (a *SpillAggregator) Keys() []uint64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	keys := make([]uint64, 0, len(a.counts))
+	for k := range a.counts {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// Count returns the total number of samples recorded for key.
+func (a *SpillAggregator) Count(key uint64) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.counts[key]
+}
+
+// Samples reassembles every sample recorded for key, reading any spilled
+// portion back off disk. Only this one key's full sample set is materialized
+// at a time.
+func (a *SpillAggregator) Samples(key uint64) ([]float32, error) {
+	// Held through the file read so a concurrent Add cannot flush into the
+	// scratch file between the tail snapshot and the read.
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	f, wasSpilled := a.spilled[key]
+	tail := append([]float32(nil), a.mem[key]...)
+
+	if !wasSpilled {
+		return tail, nil
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	head := make([]float32, len(data)/4)
+	for i := range head {
+		head[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:]))
+	}
+	return append(head, tail...), nil
+}
+
+// Close removes every scratch file created by Add. The SpillAggregator must
+// not be used afterward.
+func (a *SpillAggregator) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var first error
+	for key, f := range a.spilled {
+		name := f.Name()
+		if err := f.Close(); err != nil && first == nil {
+			first = err
+		}
+		if err := os.Remove(name); err != nil && first == nil {
+			first = err
+		}
+		delete(a.spilled, key)
+	}
+	return first
+}
@@ -1,181 +1,201 @@
-package main
-
-import (
-	"encoding/binary"
-	"errors"
-	"fmt"
-	"io"
-	"math"
-	"os"
-	"path/filepath"
-)
-
-type LatencyInfo struct {
-	Mean   float64
-	StdDev float64
-}
-
-func main() {
-	if len(os.Args) < 2 {
-		fmt.Println("Usage: program <directory>")
-		return
-	}
-
-	dirPath := os.Args[1]
-
-	// Read all files in the directory
-	files, err := os.ReadDir(dirPath)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error reading directory: %v\n", err)
-		os.Exit(1)
-	}
-
-	var filePaths []string
-	for _, file := range files {
-		// Skip directories, only include files
-		if file.IsDir() {
-			continue
-		}
-		filePaths = append(filePaths, filepath.Join(dirPath, file.Name()))
-	}
-
-	dictionary := make(map[uint64][]float32)
-
-	// Process each file
-	for _, filePath := range filePaths {
-		err := processFile(filePath, dictionary)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error processing %s: %v\n", filePath, err)
-			continue
-		}
-	}
-
-	fmt.Printf("Dictionary has %d unique address pairs.\n", len(dictionary))
-
-	// TODO: uint64 key -> ip address -> city -> link
-	result := make(map[uint64]LatencyInfo)
-	c := 0
-	for key, value := range dictionary {
-		mean, stddev, err := SigmaClipMeanStdDev(value, 3, 3)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
-		}
-		result[key] = LatencyInfo{Mean: mean, StdDev: stddev}
-		c++
-		if c < 10 {
-			fmt.Printf("mean: %fmS, stddev: %fmS\n", mean, stddev)
-		}
-	}
-}
-
-func processFile(filename string, dictionary map[uint64][]float32) error {
-	file, err := os.Open(filename)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	const structSize = 20
-	buffer := make([]byte, structSize*1024*1024) // 10MB buffer
-
-	for {
-		n, err := file.Read(buffer)
-		if n == 0 {
-			break
-		}
-
-		count := n / structSize
-		for i := range count {
-			offset := i * structSize
-
-			// Read floats directly as uint32, then convert
-			rtt1 := float32frombits(buffer[offset:])
-			rtt2 := float32frombits(buffer[offset+4:])
-			rtt3 := float32frombits(buffer[offset+8:])
-
-			// Read addresses
-			dstAddr := binary.NativeEndian.Uint32(buffer[offset+12:])
-			srcAddr := binary.NativeEndian.Uint32(buffer[offset+16:])
-
-			// Create key with smaller address first
-			var key uint64
-			if dstAddr < srcAddr {
-				key = (uint64(dstAddr) << 32) | uint64(srcAddr)
-			} else {
-				key = (uint64(srcAddr) << 32) | uint64(dstAddr)
-			}
-
-			// Append all three RTTs at once
-			dictionary[key] = append(dictionary[key], rtt1, rtt2, rtt3)
-		}
-
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
-func SigmaClipMeanStdDev(
-	data []float32,
-	k float64,
-	maxIter int,
-) (mean, stddev float64, err error) {
-	for iter := 0; iter < maxIter; iter++ {
-		n := float64(len(data))
-		if n == 0 {
-			err = errors.New("empty")
-			return
-		}
-
-		// Mean
-		mean = 0
-		for _, v := range data {
-			mean += float64(v)
-		}
-		if math.IsInf(mean, 0) {
-			err = errors.New("overflow - sum")
-			return
-		}
-		mean /= n
-
-		// Stddev (MLE)
-		stddev = 0
-		for _, v := range data {
-			diff := float64(v) - mean
-			stddev += diff * diff
-		}
-		if math.IsInf(stddev, 0) {
-			err = errors.New("overflow - stddev")
-			return
-		}
-		stddev = math.Sqrt(stddev / n)
-
-		// Clip
-		filtered := make([]float32, 0, len(data)) // 필수: 길이 할당
-		threshold := k * stddev
-		for _, v := range data {
-			if math.Abs(float64(v)-mean) <= threshold {
-				filtered = append(filtered, v)
-			}
-		}
-
-		// Converged?
-		if len(filtered) == len(data) {
-			break
-		}
-
-		data = filtered
-	}
-
-	return
-}
-
-func float32frombits(b []byte) float32 {
-	bits := binary.NativeEndian.Uint32(b)
-	return math.Float32frombits(bits)
-}
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/netip"
+	"os"
+	"runtime"
+
+	"main/pkg/estimator"
+	"main/pkg/geolink"
+	"main/pkg/ingest"
+)
+
+func main() {
+	var geoipPath string
+	var outPath string
+	var workers int
+	var endianFlag string
+	var spillThreshold int
+	var spillDir string
+	var estimatorFlag string
+	var k float64
+	var maxIter int
+	flag.StringVar(&geoipPath, "geoip", "", "path to a geolocation CSV (MaxMind GeoLite2 or IPinfo lite, flattened); enables topology output")
+	flag.StringVar(&outPath, "out", "", "path to write the topology JSON (default: stdout)")
+	flag.IntVar(&workers, "workers", runtime.NumCPU(), "number of goroutines to shard file ingestion across")
+	flag.StringVar(&endianFlag, "endian", "native", "byte order of the captured record files: le, be, or native")
+	flag.IntVar(&spillThreshold, "spill-threshold", 0, "flush a link's samples to a scratch file once it holds this many entries (0 disables spilling, keeping everything in memory)")
+	flag.StringVar(&spillDir, "spill-dir", "", "directory for spill scratch files (default: os.TempDir())")
+	flag.StringVar(&estimatorFlag, "estimator", "sigma", "outlier rejection method: sigma, median (median/MAD), or huber")
+	flag.Float64Var(&k, "k", 3, "rejection threshold in standard deviations (sigma/median) or scaled MADs (huber: the c parameter, default overridden to 1.345 below)")
+	flag.IntVar(&maxIter, "max-iter", 3, "maximum rejection iterations")
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		fmt.Println("Usage: program [--geoip=geo.csv] [--out=topology.json] [--estimator=sigma|median|huber] <directory>")
+		return
+	}
+	dirPath := flag.Arg(0)
+
+	endian, err := ingest.ParseEndian(endianFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	est, err := newEstimator(estimatorFlag, k, maxIter)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	// One SpillAggregator per worker, so the hot path (one Add call per
+	// record) only ever contends its own worker's lock instead of a single
+	// lock shared across every goroutine. paths is listed once and shared
+	// between ResolveWorkerCount and ProcessPaths so the two can't disagree
+	// on the worker count. Workers are merged into agg, by key, once
+	// ingestion completes.
+	paths, err := ingest.ListDir(dirPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading directory: %v\n", err)
+		os.Exit(1)
+	}
+	workerCount := ingest.ResolveWorkerCount(paths, workers)
+	workerAggs := make([]*ingest.SpillAggregator, workerCount)
+	for i := range workerAggs {
+		workerAggs[i] = ingest.NewSpillAggregator(spillThreshold, spillDir)
+	}
+
+	err = ingest.ProcessPaths(paths, ingest.Options{Workers: workers, Endian: endian}, func(worker int, rec ingest.Record) {
+		key := ingest.PackKey(rec.SrcAddr, rec.DstAddr)
+		if err := workerAggs[worker].Add(key, rec.RTT1, rec.RTT2, rec.RTT3); err != nil {
+			fmt.Fprintf(os.Stderr, "Error spilling samples for key %x: %v\n", key, err)
+		}
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading directory: %v\n", err)
+		os.Exit(1)
+	}
+
+	agg := ingest.NewSpillAggregator(spillThreshold, spillDir)
+	defer agg.Close()
+	for _, wa := range workerAggs {
+		defer wa.Close()
+		for _, key := range wa.Keys() {
+			samples, err := wa.Samples(key)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error reading samples for key %x: %v\n", key, err)
+				continue
+			}
+			if err := agg.Add(key, samples...); err != nil {
+				fmt.Fprintf(os.Stderr, "Error merging samples for key %x: %v\n", key, err)
+			}
+		}
+	}
+
+	keys := agg.Keys()
+	fmt.Printf("Dictionary has %d unique address pairs.\n", len(keys))
+
+	if geoipPath == "" {
+		printSummary(agg, keys, est)
+		return
+	}
+
+	resolver, err := geolink.LoadCSV(geoipPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading geoip database: %v\n", err)
+		os.Exit(1)
+	}
+
+	topoAgg := geolink.NewAggregator(resolver)
+	for _, key := range keys {
+		samples, err := agg.Samples(key)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading samples for key %x: %v\n", key, err)
+			continue
+		}
+		src, dst := splitKey(key, endian.ByteOrder())
+		topoAgg.AddSamples(src, dst, samples...)
+	}
+
+	topology := topoAgg.Build(est.Estimate)
+
+	out := os.Stdout
+	if outPath != "" {
+		out, err = os.Create(outPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating output file: %v\n", err)
+			os.Exit(1)
+		}
+		defer out.Close()
+	}
+
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(topology); err != nil {
+		fmt.Fprintf(os.Stderr, "Error encoding topology: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "Topology has %d nodes and %d links.\n", len(topology.Nodes), len(topology.Edges))
+}
+
+// newEstimator builds the estimator.Estimator named by flag, defaulting k to
+// 1.345 (95% Gaussian efficiency) for huber if the caller left -k at its
+// sigma/median-oriented default of 3.
+func newEstimator(name string, k float64, maxIter int) (estimator.Estimator, error) {
+	switch name {
+	case "sigma":
+		return estimator.SigmaClip{K: k, MaxIter: maxIter}, nil
+	case "median":
+		return estimator.MedianMAD{K: k, MaxIter: maxIter}, nil
+	case "huber":
+		if k == 3 {
+			k = 1.345
+		}
+		return estimator.Huber{C: k, MaxIter: maxIter}, nil
+	default:
+		return nil, fmt.Errorf("unknown --estimator %q (want sigma, median, or huber)", name)
+	}
+}
+
+// printSummary preserves the original, geoip-less behaviour: a compact
+// per-link line, now from the richer Estimate record.
+func printSummary(agg *ingest.SpillAggregator, keys []uint64, est estimator.Estimator) {
+	c := 0
+	for _, key := range keys {
+		samples, err := agg.Samples(key)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		stats, err := est.Estimate(samples)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		c++
+		if c < 10 {
+			fmt.Printf("n=%d kept=%d mean=%fmS stddev=%fmS median=%fmS mad=%fmS\n",
+				stats.N, stats.NKept, stats.Mean, stats.StdDev, stats.Median, stats.MAD)
+		}
+	}
+}
+
+// splitKey reverses the smaller-address-first packing done by ingest.PackKey.
+// order must be the same byte order the records were decoded with, or the
+// reconstructed addresses come out byte-swapped.
+func splitKey(key uint64, order binary.ByteOrder) (a, b netip.Addr) {
+	a = addrFromUint32(uint32(key>>32), order)
+	b = addrFromUint32(uint32(key), order)
+	return
+}
+
+func addrFromUint32(v uint32, order binary.ByteOrder) netip.Addr {
+	var bytes [4]byte
+	order.PutUint32(bytes[:], v)
+	return netip.AddrFrom4(bytes)
+}